@@ -0,0 +1,109 @@
+// Command admin runs the maintenance tasks of the authorizer database that
+// must not run on every cold start:
+//
+//	admin bootstrap [-usage-retention 9600h]
+//	admin migrate [-batch-size 500] [-dry-run]
+//	admin migrate-mysql
+//
+// It connects to USER_VAR_DB_MONGO_URI, and migrate-mysql to
+// USER_VAR_DB_MYSQL_DSN, like the function does.
+package main
+
+import (
+	mongodb "apikey/internal/repository"
+	mysqldb "apikey/internal/repository/mysql"
+	"apikey/pkg/env"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "bootstrap":
+		err = bootstrap(logger, args)
+	case "migrate":
+		err = migrate(logger, args)
+	case "migrate-mysql":
+		err = migrateMySQL(logger)
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		logger.Fatalf("Error running %s: %v", os.Args[1], err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: admin bootstrap [-usage-retention duration]")
+	fmt.Fprintln(os.Stderr, "       admin migrate [-batch-size n] [-dry-run]")
+	fmt.Fprintln(os.Stderr, "       admin migrate-mysql")
+}
+
+// bootstrap creates the indexes and records the schema version, usage is
+// kept 400 days by default so a year of reports is always available.
+func bootstrap(logger *logrus.Logger, args []string) error {
+	flags := flag.NewFlagSet("bootstrap", flag.ExitOnError)
+	retention := flags.Duration("usage-retention", 400*24*time.Hour, "how long daily usage is kept")
+	flags.Parse(args)
+
+	db, err := mongodb.Connection()
+	if err != nil {
+		return err
+	}
+	defer db.Disconnect(context.Background())
+
+	return mongodb.Bootstrap(context.Background(), logger, db, mongodb.BootstrapOptions{UsageRetention: *retention})
+}
+
+// migrate rewrites the api keys below the schema version of this build, it
+// can be run again after an interruption or a failure.
+func migrate(logger *logrus.Logger, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	batchSize := flags.Int("batch-size", 500, "documents rewritten per batch")
+	dryRun := flags.Bool("dry-run", false, "only count the documents to migrate")
+	flags.Parse(args)
+
+	db, err := mongodb.Connection()
+	if err != nil {
+		return err
+	}
+	defer db.Disconnect(context.Background())
+
+	_, err = mongodb.MigrateApiKeys(context.Background(), logger, db, mongodb.MigrateOptions{
+		BatchSize: *batchSize,
+		DryRun:    *dryRun,
+	})
+	return err
+}
+
+// migrateMySQL applies the MySQL migrations of this build, runs started at
+// the same time wait for each other.
+func migrateMySQL(logger *logrus.Logger) error {
+	ctx := context.Background()
+	dsn, err := env.Get(ctx, "USER_VAR_DB_MYSQL_DSN")
+	if err != nil {
+		return err
+	}
+	db, err := mysqldb.Connection(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return mysqldb.Migrate(ctx, logger, db)
+}
@@ -3,34 +3,336 @@ package main
 import (
 	"apikey/internal/api/handler"
 	"apikey/internal/api/server"
+	"apikey/internal/model/apikey"
+	"apikey/internal/notify"
+	"apikey/internal/ratelimit"
 	mongodb "apikey/internal/repository"
 	mysql "apikey/internal/repository"
+	mysqldb "apikey/internal/repository/mysql"
 	"apikey/internal/service"
+	"apikey/internal/snapshot"
+	"apikey/pkg/clock"
 	"apikey/pkg/env"
 	"apikey/pkg/logger"
-	"log"
+	"apikey/pkg/metrics"
+	"apikey/pkg/tracing"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
-func init() {
+// run builds the server from the environment, every dependency the
+// handlers need is created here.
+func run() (*server.Server, error) {
 	if err := env.Validate(env.GetEnvs()); err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	logger, err := logger.New()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	db, err := mongodb.Connection()
+	emitter, err := newMetrics()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	companyRepository := mysql.NewApiKeyRepository(logger, db, "api_key_db", "api_key")
-	companyService := service.NewApiKeyService(logger, companyRepository)
-	companyHandler := handler.NewApiKeyHandler(logger, companyService)
+	tracerProvider, err := tracing.New(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	tracer := tracerProvider.Tracer(tracing.Name)
 
-	svr = server.New(
-		server.WithApiKeyHandler(companyHandler),
+	db, err := mongodb.Connection(
+		mongodb.MetricsMonitor(emitter),
+		otelmongo.NewMonitor(otelmongo.WithTracerProvider(tracerProvider)),
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter, counter, err := rateLimitBackend(db)
+	if err != nil {
+		return nil, err
+	}
+
+	policies, err := planPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	notifier, err := quotaNotifier(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	failPolicy, err := failPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	companyRepository, usageRepository, healthRepository, err := apikeyBackend(logger, db)
+	if err != nil {
+		return nil, err
+	}
+
+	commerceRepository := mysql.NewCompanyRepository(logger, db, mysql.Database, mysql.CompanyCollection)
+	gracePeriodRepository := mysql.NewGracePeriodRepository(logger, db, mysql.Database, mysql.CommerceRuleCollection)
+	companyService := service.NewApiKeyService(logger, companyRepository,
+		service.WithLimiter(limiter),
+		service.WithCounter(counter),
+		service.WithCompanyRepository(commerceRepository),
+		service.WithUsageRepository(usageRepository),
+		service.WithGracePeriodRepository(gracePeriodRepository),
+		service.WithPlanPolicies(policies),
+		service.WithNotifier(notifier),
+		service.WithFailPolicy(failPolicy, snapshot.New(logger, clock.System, snapshotPath())),
+		service.WithMetrics(emitter),
+		service.WithTracer(tracer),
+	)
+	companyHandler := handler.NewApiKeyHandler(logger, companyService,
+		handler.WithPaymentConfig(paymentConfig()),
+		handler.WithMetrics(emitter),
+		handler.WithTracer(tracer),
+	)
+
+	usageService := service.NewUsageService(logger, usageRepository)
+	usageHandler := handler.NewUsageHandler(logger, usageService)
+
+	// The admin routes are only served with USER_VAR_ADMIN_TOKEN, which can
+	// reference a secret like the database uri.
+	adminToken, err := env.Get(context.Background(), "USER_VAR_ADMIN_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	if adminToken == "" {
+		logger.Infof("Admin routes disabled, USER_VAR_ADMIN_TOKEN not set")
+	}
+
+	healthService := service.NewHealthService(logger, healthRepository, configVersion(), service.WithCaches(companyService))
+	healthHandler := handler.NewHealthHandler(logger, healthService)
+
+	return server.New(
+		server.WithApiKeyHandler(companyHandler),
+		server.WithUsageHandler(usageHandler),
+		server.WithAdminToken(adminToken),
+		server.WithHealthHandler(healthHandler),
+		server.WithLogger(logger),
+		server.WithFlusher(companyService.Flush),
+		server.WithFlusher(tracerProvider.ForceFlush),
+		server.WithFlusher(flushLogs(logger)),
+	), nil
+}
+
+// apikeyBackend picks where api keys and their usage live from
+// USER_VAR_DB_BACKEND: mongo (default) or mysql, the latter connecting to
+// USER_VAR_DB_MYSQL_DSN. The health repository pings the same database.
+// MySQL migrations are applied by "admin migrate-mysql", not on cold start.
+func apikeyBackend(logger *logrus.Logger, db *mongo.Client) (mysql.ApiKeyRepository, mysql.UsageRepository, mysql.HealthRepository, error) {
+	switch backend := os.Getenv("USER_VAR_DB_BACKEND"); backend {
+	case "", "mongo":
+		return mysql.NewApiKeyRepository(logger, db, mysql.Database, mysql.ApiKeyCollection),
+			mysql.NewUsageRepository(logger, db, mysql.Database, mysql.UsageCollection),
+			mysql.NewHealthRepository(logger, db), nil
+	case "mysql":
+		ctx := context.Background()
+		dsn, err := env.Get(ctx, "USER_VAR_DB_MYSQL_DSN")
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		sqlDB, err := mysqldb.Connection(ctx, dsn)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return mysqldb.NewApiKeyRepository(logger, sqlDB), mysqldb.NewUsageRepository(logger, sqlDB),
+			mysqldb.NewHealthRepository(logger, sqlDB), nil
+	default:
+		return nil, nil, nil, fmt.Errorf("not a valid database backend: %s", backend)
+	}
+}
+
+// rateLimitBackend picks where rate limit buckets and usage counters live
+// from USER_VAR_RATE_LIMIT_BACKEND: memory (default), mongo or redis, the
+// latter connecting to USER_VAR_REDIS_URL. Memory counters are per
+// container, so are the limits and the threshold alerts they enforce.
+func rateLimitBackend(db *mongo.Client) (ratelimit.Limiter, ratelimit.Counter, error) {
+	switch backend := os.Getenv("USER_VAR_RATE_LIMIT_BACKEND"); backend {
+	case "", "memory":
+		return ratelimit.NewMemoryLimiter(clock.System), ratelimit.NewMemoryCounter(clock.System), nil
+	case "mongo":
+		return ratelimit.NewMongoLimiter(db, mongodb.Database, mongodb.LimitCollection, clock.System),
+			ratelimit.NewMongoCounter(db, mongodb.Database, mongodb.LimitCollection, clock.System), nil
+	case "redis":
+		url, err := env.Get(context.Background(), "USER_VAR_REDIS_URL")
+		if err != nil {
+			return nil, nil, err
+		}
+		opts, err := redis.ParseURL(url)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid USER_VAR_REDIS_URL: %w", err)
+		}
+		client := redis.NewClient(opts)
+		return ratelimit.NewRedisLimiter(client, clock.System), ratelimit.NewRedisCounter(client, clock.System), nil
+	default:
+		return nil, nil, fmt.Errorf("not a valid rate limit backend: %s", backend)
+	}
+}
+
+// paymentConfig reads the payment routes from USER_VAR_PAYMENT_ROUTES, a
+// comma separated list such as "POST /payments,POST /refunds", and where
+// the amount is sent from USER_VAR_PAYMENT_AMOUNT_HEADER and
+// USER_VAR_PAYMENT_AMOUNT_QUERY.
+func paymentConfig() handler.PaymentConfig {
+	config := handler.PaymentConfig{
+		AmountHeader: "x-transaction-amount",
+		AmountQuery:  "amount",
+	}
+	for _, route := range strings.Split(os.Getenv("USER_VAR_PAYMENT_ROUTES"), ",") {
+		if route = strings.TrimSpace(route); route != "" {
+			config.Routes = append(config.Routes, route)
+		}
+	}
+	if header := os.Getenv("USER_VAR_PAYMENT_AMOUNT_HEADER"); header != "" {
+		config.AmountHeader = header
+	}
+	if query := os.Getenv("USER_VAR_PAYMENT_AMOUNT_QUERY"); query != "" {
+		config.AmountQuery = query
+	}
+	return config
+}
+
+// planPolicies reads the soft thresholds and overage of each plan from
+// USER_VAR_PLAN_POLICIES, e.g.
+// {"default":{"thresholds":[80,100]},"premium":{"thresholds":[80,100],"overage":10}}.
+func planPolicies() (map[string]apikey.PlanPolicy, error) {
+	policies := map[string]apikey.PlanPolicy{}
+	value := os.Getenv("USER_VAR_PLAN_POLICIES")
+	if value == "" {
+		return policies, nil
+	}
+	if err := json.Unmarshal([]byte(value), &policies); err != nil {
+		return nil, fmt.Errorf("invalid USER_VAR_PLAN_POLICIES: %w", err)
+	}
+	return policies, nil
+}
+
+// quotaNotifier puts threshold alerts on the EventBridge bus named by
+// USER_VAR_ALERT_EVENT_BUS, or logs them when it is not set.
+func quotaNotifier(logger *logrus.Logger) (notify.Notifier, error) {
+	bus := os.Getenv("USER_VAR_ALERT_EVENT_BUS")
+	if bus == "" {
+		return notify.NewLogNotifier(logger), nil
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return notify.NewEventBridgeNotifier(eventbridge.NewFromConfig(cfg), bus, "apikey.authorizer"), nil
+}
+
+// failPolicy reads what to do when the database is down from
+// USER_VAR_FAIL_MODE: closed (default), static or open. Static serves keys
+// seen within USER_VAR_FAIL_STATIC_MAX_STALENESS (15m by default), open also
+// allows the client ids or key fingerprints in USER_VAR_FAIL_OPEN_CLIENTS.
+func failPolicy() (service.FailPolicy, error) {
+	policy := service.FailPolicy{Mode: service.FailClosed, MaxStaleness: 15 * time.Minute}
+
+	switch mode := os.Getenv("USER_VAR_FAIL_MODE"); mode {
+	case "", service.FailClosed:
+	case service.FailStatic, service.FailOpen:
+		policy.Mode = mode
+	default:
+		return policy, fmt.Errorf("not a valid fail mode: %s", mode)
+	}
+
+	if value := os.Getenv("USER_VAR_FAIL_STATIC_MAX_STALENESS"); value != "" {
+		staleness, err := time.ParseDuration(value)
+		if err != nil {
+			return policy, fmt.Errorf("invalid USER_VAR_FAIL_STATIC_MAX_STALENESS: %w", err)
+		}
+		policy.MaxStaleness = staleness
+	}
+
+	for _, client := range strings.Split(os.Getenv("USER_VAR_FAIL_OPEN_CLIENTS"), ",") {
+		if client = strings.TrimSpace(client); client != "" {
+			policy.OpenClients = append(policy.OpenClients, client)
+		}
+	}
+	return policy, nil
+}
+
+// snapshotPath is where the last known good keys are kept between
+// invocations, USER_VAR_SNAPSHOT_PATH or the Lambda /tmp.
+func snapshotPath() string {
+	if path := os.Getenv("USER_VAR_SNAPSHOT_PATH"); path != "" {
+		return path
+	}
+	return "/tmp/apikey-snapshot.json"
+}
+
+// newMetrics writes CloudWatch EMF lines to stdout under
+// USER_VAR_METRICS_NAMESPACE unless USER_VAR_METRICS is "none". Client ids
+// are only made a dimension when USER_VAR_METRICS_CLIENT_DIMENSION is true,
+// otherwise they are kept as a property of the line.
+func newMetrics() (metrics.Metrics, error) {
+	switch value := os.Getenv("USER_VAR_METRICS"); value {
+	case "", "emf":
+	case "none":
+		return metrics.Nop, nil
+	default:
+		return nil, fmt.Errorf("not a valid metrics output: %s", value)
+	}
+
+	namespace := os.Getenv("USER_VAR_METRICS_NAMESPACE")
+	if namespace == "" {
+		namespace = "ApiKeyAuthorizer"
+	}
+
+	var options []metrics.EMFOption
+	if os.Getenv("USER_VAR_METRICS_CLIENT_DIMENSION") != "true" {
+		options = append(options, metrics.WithHighCardinality(metrics.ClientID))
+	}
+	return metrics.NewEMF(os.Stdout, namespace, options...), nil
+}
+
+// flushLogs sends the entries buffered for Graylog at the end of every
+// invocation.
+func flushLogs(log *logrus.Logger) server.Flusher {
+	return func(ctx context.Context) error {
+		return logger.Flush(ctx, log)
+	}
+}
+
+// configVersion identifies the configuration the function runs with,
+// USER_VAR_CONFIG_VERSION or a hash of the USER_VAR_ variables.
+func configVersion() string {
+	if version := os.Getenv("USER_VAR_CONFIG_VERSION"); version != "" {
+		return version
+	}
+
+	var variables []string
+	for _, variable := range os.Environ() {
+		if strings.HasPrefix(variable, "USER_VAR_") {
+			variables = append(variables, variable)
+		}
+	}
+	sort.Strings(variables)
+
+	sum := sha256.Sum256([]byte(strings.Join(variables, "\n")))
+	return hex.EncodeToString(sum[:])[:12]
 }
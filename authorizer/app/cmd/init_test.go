@@ -1,65 +1,56 @@
 package main
 
-/*
 import (
-	"context"
-	"log"
-	"os"
 	"testing"
-
-	"github.com/aws/aws-lambda-go/events"
 )
 
-func TestGetCompanyHandler(t *testing.T) {
-	// Configura el manejador y la función de limpieza
-
-	svr, err := run()
-	if err != nil {
-		log.Fatalf("error runnig lambda server: %v", err)
-	}
-	// Define una solicitud de API Gateway simulada
-	input := events.APIGatewayProxyRequest{
-		HTTPMethod: "GET",
-		Path:       "/test",
-	}
-
-	// Invoca el manejador Lambda
-	_, err = svr.Route()(context.Background(), input)
-	if err != nil {
-		t.Fatalf("error invoking get commerce: %v", err)
-	}
-
+func setupEnv(t *testing.T) {
+	t.Setenv("USER_VAR_LOG_CHAN", "stdout")
+	t.Setenv("USER_VAR_LOG_LEVEL", "info")
+	t.Setenv("USER_VAR_DB_MONGO_URI", "mongodb://localhost:27017")
 }
 
 func TestEnvMising(t *testing.T) {
 	// Valida que la función devolvió un error al validar las envs al tener setupEnv
-	os.Setenv("USER_VAR_DB_HOST", "")
+	setupEnv(t)
+	t.Setenv("USER_VAR_DB_MONGO_URI", "")
+
 	_, err := run()
 	if err == nil {
 		t.Error("expected env error")
 	}
+}
+
+func TestGraylogAddrMising(t *testing.T) {
+	// Valida que GRAYLOG_ADDR es obligatoria al enviar los logs a graylog
+	setupEnv(t)
+	t.Setenv("USER_VAR_LOG_CHAN", "stdgraylog")
+	t.Setenv("GRAYLOG_ADDR", "")
 
+	_, err := run()
+	if err == nil {
+		t.Error("expected env error")
+	}
 }
 
 func TestLoggerMisingConfig(t *testing.T) {
 	// Valida que la función devolvió un error el logger
-	os.Setenv("USER_VAR_LOG_LEVEL", "INCORRECTLOGLEVEL")
+	setupEnv(t)
+	t.Setenv("USER_VAR_LOG_LEVEL", "INCORRECTLOGLEVEL")
 
 	_, err := run()
-
 	if err == nil {
-		t.Error("expected env error")
+		t.Error("expected logger error")
 	}
-
 }
 
 func TestDbConnectionFail(t *testing.T) {
-	os.Setenv("USER_VAR_DB_HOST", "invalid-host")
-	// Valida que la función devolvió un error al validar las envs
+	// Valida que la función devolvió un error al conectar con una uri invalida
+	setupEnv(t)
+	t.Setenv("USER_VAR_DB_MONGO_URI", "invalid-uri")
+
 	_, err := run()
 	if err == nil {
 		t.Error("expected db fail error")
 	}
-
 }
-*/
@@ -1,17 +1,20 @@
 package main
 
 import (
-	"apikey/internal/api/server"
+	"log"
+
+	_ "time/tzdata"
 
 	_ "github.com/go-sql-driver/mysql"
 
 	"github.com/aws/aws-lambda-go/lambda"
 )
 
-var svr *server.Server
-
 func main() {
+	svr, err := run()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	lambda.Start(svr.Route())
-
 }
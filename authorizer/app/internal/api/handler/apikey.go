@@ -2,48 +2,264 @@ package handler
 
 import (
 	"apikey/internal/api/resp"
+	"apikey/internal/errormap"
+	"apikey/internal/model"
+	"apikey/internal/model/apikey"
 	"apikey/internal/service"
+	"apikey/pkg/metrics"
+	"apikey/pkg/tracing"
 	"context"
-	"fmt"
+	"errors"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const INVALID_COMMERCE = "invalid commerce id: %v"
 
+const (
+	apiKeyHeader        = "x-api-key"
+	authorizationHeader = "Authorization"
+	originHeader        = "Origin"
+	refererHeader       = "Referer"
+	languageHeader      = "Accept-Language"
+)
+
+var (
+	// ErrUnauthorized is the exact error API Gateway turns into a 401.
+	ErrUnauthorized = errors.New("Unauthorized")
+	// ErrAuthorizerFailure makes API Gateway answer 500, so infrastructure
+	// failures are not mistaken for denied keys.
+	ErrAuthorizerFailure = errors.New("authorizer failure")
+)
+
 type ApiKeyHandler interface {
-	HandleValidateApiKey(ctx context.Context, request events.APIGatewayCustomAuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error)
+	HandleValidateApiKey(ctx context.Context, request model.AuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error)
 }
 
 type apikeyHandler struct {
 	logger        *logrus.Logger
 	apikeyService service.ServiceApiKey
+	payment       PaymentConfig
+	metrics       metrics.Metrics
+	tracer        trace.Tracer
+}
+
+// PaymentConfig tells which routes move money, as "METHOD /path" prefixes,
+// and where REQUEST events carry their transaction amount.
+type PaymentConfig struct {
+	Routes       []string
+	AmountHeader string
+	AmountQuery  string
+}
+
+// IsPaymentRoute reports whether route is one of the Routes or below one of
+// them, "POST /payments" matches "POST /payments/{id}" but not
+// "POST /paymentsX".
+func (p PaymentConfig) IsPaymentRoute(route string) bool {
+	for _, prefix := range p.Routes {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if prefix != "" && (route == prefix || strings.HasPrefix(route, prefix+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+type Option func(*apikeyHandler)
+
+func WithPaymentConfig(payment PaymentConfig) Option {
+	return func(c *apikeyHandler) {
+		c.payment = payment
+	}
+}
+
+func WithMetrics(metrics metrics.Metrics) Option {
+	return func(c *apikeyHandler) {
+		c.metrics = metrics
+	}
+}
+
+func WithTracer(tracer trace.Tracer) Option {
+	return func(c *apikeyHandler) {
+		c.tracer = tracer
+	}
 }
 
-func NewApiKeyHandler(logger *logrus.Logger, apikeyService service.ServiceApiKey) ApiKeyHandler {
-	return &apikeyHandler{
+func NewApiKeyHandler(logger *logrus.Logger, apikeyService service.ServiceApiKey, options ...Option) ApiKeyHandler {
+	c := &apikeyHandler{
 		logger:        logger,
 		apikeyService: apikeyService,
+		metrics:       metrics.Nop,
+		tracer:        tracing.Nop,
+	}
+
+	for _, option := range options {
+		option(c)
 	}
+
+	return c
 }
 
-func (c *apikeyHandler) HandleValidateApiKey(ctx context.Context, request events.APIGatewayCustomAuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+func (c *apikeyHandler) HandleValidateApiKey(ctx context.Context, request model.AuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+
+	ctx, span := c.tracer.Start(ctx, "HandleValidateApiKey", trace.WithAttributes(
+		attribute.String("authorizer.type", request.Type),
+	))
+	defer span.End()
 
 	status := "Allow"
 
-	apikey := request.AuthorizationToken
+	start := time.Now()
+	access := c.newAccess(request)
+	data, err := c.apikeyService.ValidateApiKey(ctx, access)
+	c.metrics.Put(metrics.ValidationLatency, metrics.Since(start), metrics.Milliseconds,
+		metrics.Dimensions{metrics.Plan: data.UsageLimits.Plan})
 
-	data, err := c.apikeyService.ValidateApiKey(ctx, apikey)
+	if err == nil {
+		data.PlatformData = allowContext(data)
+		c.decision(ctx, access, data, status, metrics.Allow, start)
+		span.SetAttributes(attribute.String("apikey.decision", status))
+	}
 
 	if err != nil {
-		errorMessage := fmt.Sprintf(" Error %v ", err)
+		reasonCode, reasonMessage := errormap.Reason(err, locale(request, data))
+		kind := errormap.KindOf(reasonCode)
+		span.SetAttributes(attribute.String("apikey.reason_code", reasonCode))
+
+		switch kind {
+		case errormap.KindUnauthorized:
+			c.decision(ctx, access, data, "Unauthorized", reasonCode, start)
+			return events.APIGatewayCustomAuthorizerResponse{}, ErrUnauthorized
+		case errormap.KindInternal:
+			c.logger.WithContext(ctx).Errorf("Error validating apikey: %v", err)
+			c.decision(ctx, access, data, "Error", reasonCode, start)
+			span.SetStatus(codes.Error, err.Error())
+			return events.APIGatewayCustomAuthorizerResponse{}, ErrAuthorizerFailure
+		}
+
 		status = "Deny"
+		c.decision(ctx, access, data, status, reasonCode, start)
+		span.SetAttributes(attribute.String("apikey.decision", status))
 		data.ClientID = ""
 		data.PlatformData = map[string]interface{}{
-			"error": errorMessage,
+			"reasonCode":    reasonCode,
+			"reasonMessage": reasonMessage,
+			"statusCode":    kind.StatusCode(),
+		}
+
+		var deny *apikey.DenyError
+		if errors.As(err, &deny) {
+			for key, value := range deny.Context {
+				data.PlatformData[key] = value
+			}
 		}
 
 	}
-	return resp.Respond(data.PlatformData, status, data.ClientID, request.MethodArn)
+	return resp.Respond(data.PlatformData, status, data.ClientID, request.MethodArn, data.QuotaState())
+}
+
+// decision counts the outcome of a call by reason code and logs one entry
+// per invocation, correlated by the Lambda request id.
+func (c *apikeyHandler) decision(ctx context.Context, access apikey.Access, data *apikey.ApiKey, decision, reason string, start time.Time) {
+	c.metrics.Put(metrics.Decision, 1, metrics.Count, metrics.Dimensions{
+		metrics.Reason:   reason,
+		metrics.Plan:     data.UsageLimits.Plan,
+		metrics.ClientID: data.ClientID,
+	})
+
+	fields := logrus.Fields{
+		"client_id":  data.ClientID,
+		"decision":   decision,
+		"reason":     reason,
+		"route":      access.Route,
+		"latency_ms": metrics.Since(start),
+	}
+	if access.ApiKey != "" {
+		fields["key_fingerprint"] = apikey.Fingerprint(access.ApiKey)
+	}
+	c.logger.WithContext(ctx).WithFields(fields).Info("Apikey validated")
+}
+
+// allowContext adds the test flag, the quota windows and, when the key was
+// served from the snapshot, the fail mode and staleness to its platform
+// data.
+func allowContext(data *apikey.ApiKey) map[string]interface{} {
+	context := map[string]interface{}{}
+	for key, value := range data.PlatformData {
+		context[key] = value
+	}
+	if data.IsTestKey() {
+		context["testKey"] = true
+	}
+	for key, value := range apikey.QuotaContext(data.Quotas) {
+		context[key] = value
+	}
+	if data.FailMode != "" {
+		context["failMode"] = data.FailMode
+		context["staleness"] = int(data.Staleness.Seconds())
+	}
+	return context
+}
+
+// locale picks the language of the deny message: the Accept-Language of
+// REQUEST events, else the one of the key's company, else the default.
+func locale(request model.AuthorizerRequest, data *apikey.ApiKey) string {
+	if locale, ok := errormap.NegotiateLocale(request.Header(languageHeader)); ok {
+		return locale
+	}
+	if locale, ok := errormap.SupportedLocale(data.Locale); ok {
+		return locale
+	}
+	return errormap.DefaultLocale
+}
+
+// newAccess reads the key from the authorizationToken on TOKEN events and
+// from the x-api-key (or Authorization) header on REQUEST events, where the
+// Origin and Referer headers and the transaction amount are available too.
+func (c *apikeyHandler) newAccess(request model.AuthorizerRequest) apikey.Access {
+	access := apikey.Access{
+		ApiKey: request.AuthorizationToken,
+		Method: request.HTTPMethod,
+		Stage:  request.RequestContext.Stage,
+	}
+
+	if request.Type == model.RequestAuthorizer {
+		access.ApiKey = request.Header(apiKeyHeader)
+		if access.ApiKey == "" {
+			access.ApiKey = request.Header(authorizationHeader)
+		}
+		access.Origin = request.Header(originHeader)
+		access.Referer = request.Header(refererHeader)
+	}
+
+	resource := request.Resource
+	access.RouteTemplate = resource != ""
+	if methodArn, err := model.ParseMethodArn(request.MethodArn); err == nil {
+		if access.Method == "" {
+			access.Method = methodArn.Method
+		}
+		if access.Stage == "" {
+			access.Stage = methodArn.Stage
+		}
+		if resource == "" {
+			resource = "/" + methodArn.Resource
+		}
+	}
+	access.Route = access.Method + " " + resource
+
+	if c.payment.IsPaymentRoute(access.Route) {
+		access.Payment = true
+		access.Amount = request.Header(c.payment.AmountHeader)
+		if access.Amount == "" {
+			access.Amount = request.QueryStringParameters[c.payment.AmountQuery]
+		}
+	}
+
+	return access
 }
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"apikey/internal/errormap"
+	"apikey/internal/model"
+	"apikey/internal/model/apikey"
+	"apikey/internal/repository/memory"
+	"apikey/internal/service"
+	"apikey/pkg/tracing"
+	"apikey/pkg/tracing/tracingtest"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestHandleValidateApiKeySpans(t *testing.T) {
+	provider, exporter := tracingtest.NewInMemory()
+	tracer := provider.Tracer(tracing.Name)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	repository := memory.NewApiKeyRepository()
+	repository.Put("key", apikey.ApiKey{
+		ClientID:  "client-1",
+		IsActive:  true,
+		ExpiredAt: time.Now().Add(time.Hour),
+	})
+	h := NewApiKeyHandler(logger,
+		service.NewApiKeyService(logger, repository, service.WithTracer(tracer)),
+		WithTracer(tracer),
+	)
+
+	var request model.AuthorizerRequest
+	request.Type = model.TokenAuthorizer
+	request.AuthorizationToken = "key"
+	request.MethodArn = "arn:aws:execute-api:us-east-1:123456789012:api/prod/GET/payments"
+
+	if _, err := h.HandleValidateApiKey(context.Background(), request); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	serviceSpan, handlerSpan := spans[0], spans[1]
+	if handlerSpan.Name != "HandleValidateApiKey" || serviceSpan.Name != "ServiceApiKey.ValidateApiKey" {
+		t.Fatalf("spans = %q, %q", handlerSpan.Name, serviceSpan.Name)
+	}
+	if serviceSpan.Parent.SpanID() != handlerSpan.SpanContext.SpanID() {
+		t.Errorf("service span is not a child of the handler span")
+	}
+
+	attributes := map[string]string{}
+	for _, attribute := range append(handlerSpan.Attributes, serviceSpan.Attributes...) {
+		attributes[string(attribute.Key)] = attribute.Value.Emit()
+	}
+	if attributes["apikey.decision"] != "Allow" || attributes["apikey.client_id"] != "client-1" {
+		t.Errorf("attributes = %v", attributes)
+	}
+}
+
+func TestIsPaymentRoute(t *testing.T) {
+	payment := PaymentConfig{Routes: []string{"POST /payments", "PUT /refunds/"}}
+	tests := []struct {
+		route string
+		want  bool
+	}{
+		{"POST /payments", true},
+		{"POST /payments/123", true},
+		{"POST /paymentsX", false},
+		{"POST /payment", false},
+		{"GET /payments", false},
+		{"PUT /refunds", true},
+		{"PUT /refunds/9", true},
+		{"PUT /refundsX", false},
+	}
+	for _, test := range tests {
+		if got := payment.IsPaymentRoute(test.route); got != test.want {
+			t.Errorf("IsPaymentRoute(%q) = %v, want %v", test.route, got, test.want)
+		}
+	}
+	if (PaymentConfig{Routes: []string{""}}).IsPaymentRoute("POST /payments") {
+		t.Error("an empty route must not match")
+	}
+}
+
+func newTestHandler(t *testing.T, keys map[string]apikey.ApiKey) (ApiKeyHandler, *memory.ApiKeyRepository) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	repository := memory.NewApiKeyRepository()
+	for value, key := range keys {
+		repository.Put(value, key)
+	}
+	return NewApiKeyHandler(logger, service.NewApiKeyService(logger, repository)), repository
+}
+
+func tokenRequest(key string) model.AuthorizerRequest {
+	var request model.AuthorizerRequest
+	request.Type = model.TokenAuthorizer
+	request.AuthorizationToken = key
+	request.MethodArn = "arn:aws:execute-api:us-east-1:123456789012:api/prod/GET/payments"
+	return request
+}
+
+func TestHandleValidateApiKeyDenyContext(t *testing.T) {
+	h, _ := newTestHandler(t, map[string]apikey.ApiKey{
+		"expired":   {ClientID: "client-1", IsActive: true, ExpiredAt: time.Now().Add(-time.Hour)},
+		"no-expiry": {ClientID: "client-2", IsActive: true},
+	})
+
+	tests := []struct {
+		key    string
+		reason string
+	}{
+		{"expired", errormap.ReasonKeyExpired},
+		{"no-expiry", errormap.ReasonInvalidKeyConfig},
+	}
+	for _, test := range tests {
+		response, err := h.HandleValidateApiKey(context.Background(), tokenRequest(test.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if effect := response.PolicyDocument.Statement[0].Effect; effect != "Deny" {
+			t.Errorf("%s: effect = %s, want Deny", test.key, effect)
+		}
+		if response.Context["reasonCode"] != test.reason {
+			t.Errorf("%s: reasonCode = %v, want %s", test.key, response.Context["reasonCode"], test.reason)
+		}
+		if _, ok := response.Context["error"]; ok {
+			t.Errorf("%s: context %v exposes the error", test.key, response.Context)
+		}
+	}
+}
+
+func TestHandleValidateApiKeyUnauthorized(t *testing.T) {
+	h, _ := newTestHandler(t, map[string]apikey.ApiKey{
+		"key": {ClientID: "client-1", IsActive: true, ExpiredAt: time.Now().Add(time.Hour)},
+	})
+
+	for _, key := range []string{"", "unknown"} {
+		response, err := h.HandleValidateApiKey(context.Background(), tokenRequest(key))
+		if err != ErrUnauthorized {
+			t.Errorf("key %q: err = %v, want %v", key, err, ErrUnauthorized)
+		}
+		if len(response.PolicyDocument.Statement) != 0 {
+			t.Errorf("key %q: got a policy %+v", key, response.PolicyDocument)
+		}
+	}
+}
+
+func TestHandleValidateApiKeyInfrastructureFailure(t *testing.T) {
+	h, repository := newTestHandler(t, map[string]apikey.ApiKey{
+		"key": {ClientID: "client-1", IsActive: true, ExpiredAt: time.Now().Add(time.Hour)},
+	})
+	repository.Fail(errors.New("connection refused"))
+
+	response, err := h.HandleValidateApiKey(context.Background(), tokenRequest("key"))
+	if err != ErrAuthorizerFailure {
+		t.Errorf("err = %v, want %v", err, ErrAuthorizerFailure)
+	}
+	if len(response.PolicyDocument.Statement) != 0 {
+		t.Errorf("got a policy %+v", response.PolicyDocument)
+	}
+}
+
+func TestHandleValidateApiKeyRecordsTokenUsage(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	repository := memory.NewApiKeyRepository()
+	repository.Put("key", apikey.ApiKey{ClientID: "client-1", IsActive: true, ExpiredAt: time.Now().Add(time.Hour), Timezone: "UTC"})
+	usage := memory.NewUsageRepository()
+	h := NewApiKeyHandler(logger, service.NewApiKeyService(logger, repository, service.WithUsageRepository(usage)))
+
+	from := time.Now().UTC().Format("2006-01-02")
+	if _, err := h.HandleValidateApiKey(context.Background(), tokenRequest("key")); err != nil {
+		t.Fatal(err)
+	}
+
+	to := time.Now().UTC().Format("2006-01-02")
+	series, err := usage.Series(context.Background(), "client-1", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 1 || series[0].Count != 1 || series[0].Routes["GET *"] != 1 {
+		t.Errorf("series = %+v, want the TOKEN call under GET *", series)
+	}
+}
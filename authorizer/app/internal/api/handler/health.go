@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"apikey/internal/model/health"
+	"apikey/internal/service"
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type HealthHandler interface {
+	HandleHealthCheck(ctx context.Context, event health.Event) (health.Report, error)
+}
+
+type healthHandler struct {
+	logger        *logrus.Logger
+	healthService service.ServiceHealth
+}
+
+func NewHealthHandler(logger *logrus.Logger, healthService service.ServiceHealth) HealthHandler {
+	return &healthHandler{
+		logger:        logger,
+		healthService: healthService,
+	}
+}
+
+// HandleHealthCheck answers warm-up pings with the health report instead of
+// validating them as keys.
+func (c *healthHandler) HandleHealthCheck(ctx context.Context, event health.Event) (health.Report, error) {
+	report := c.healthService.Check(ctx)
+	if report.Status != health.StatusOK {
+		c.logger.Warnf("Health check %s: database %s", report.Status, report.Database.Error)
+	}
+	return report, nil
+}
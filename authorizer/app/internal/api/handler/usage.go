@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"apikey/internal/api/resp"
+	"apikey/internal/service"
+	"context"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/sirupsen/logrus"
+)
+
+type UsageHandler interface {
+	HandleUsageSeries(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+	HandleTopConsumers(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+}
+
+type usageHandler struct {
+	logger       *logrus.Logger
+	usageService service.ServiceUsage
+}
+
+func NewUsageHandler(logger *logrus.Logger, usageService service.ServiceUsage) UsageHandler {
+	return &usageHandler{
+		logger:       logger,
+		usageService: usageService,
+	}
+}
+
+// HandleUsageSeries answers GET /admin/usage/{clientId}?from=&to= with the
+// daily usage of the key per route.
+func (c *usageHandler) HandleUsageSeries(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var (
+		clientID = request.PathParameters["clientId"]
+		from     = request.QueryStringParameters["from"]
+		to       = request.QueryStringParameters["to"]
+	)
+
+	series, err := c.usageService.Series(ctx, clientID, from, to)
+	if err != nil {
+		return resp.RespondError(err)
+	}
+
+	return resp.RespondJSON(series)
+}
+
+// HandleTopConsumers answers GET /admin/usage/top?from=&to=&limit= with the
+// keys that made the most calls in the range.
+func (c *usageHandler) HandleTopConsumers(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var (
+		from = request.QueryStringParameters["from"]
+		to   = request.QueryStringParameters["to"]
+	)
+
+	limit := 0
+	if value := request.QueryStringParameters["limit"]; value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return resp.RespondError(service.ErrInvalidArgument)
+		}
+		limit = n
+	}
+
+	consumers, err := c.usageService.TopConsumers(ctx, from, to, limit)
+	if err != nil {
+		return resp.RespondError(err)
+	}
+
+	return resp.RespondJSON(consumers)
+}
@@ -0,0 +1,89 @@
+package resp
+
+import (
+	"apikey/internal/errormap"
+	"apikey/internal/model"
+	"apikey/pkg/errorx"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// RespondJSON answers an admin API request with body wrapped in a
+// TemplateResponse.
+func RespondJSON(data interface{}) (events.APIGatewayProxyResponse, error) {
+	return respondProxy(http.StatusOK, NewTemplateResponse(data))
+}
+
+// ErrorBody is the data of an admin API error.
+type ErrorBody struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// RespondError answers an admin API request with the status matching the
+// error code and the error's code name, message and fields. Errors without
+// a code are internal errors and only their status text is sent.
+func RespondError(err error) (events.APIGatewayProxyResponse, error) {
+	status := http.StatusInternalServerError
+	data := ErrorBody{Code: errormap.CodeName(errormap.CodeUnknown), Message: http.StatusText(status)}
+
+	var errx errorx.Error
+	if errors.As(err, &errx) {
+		status = HTTPStatus(errx.Code())
+		data = ErrorBody{
+			Code:    errormap.CodeName(errx.Code()),
+			Message: strings.TrimSpace(errx.Message()),
+			Fields:  errx.Fields(),
+		}
+	}
+
+	return respondProxy(status, TemplateResponse{
+		BaseResponse: model.BaseResponse{
+			Status:    "error",
+			Code:      status,
+			Datetime:  time.Now().Format(TimeLayout),
+			Timestamp: time.Now().Unix(),
+		},
+		Data: data,
+	})
+}
+
+// HTTPStatus maps error codes to HTTP status codes.
+func HTTPStatus(code errorx.ErrorCode) int {
+	switch code {
+	case errormap.CodeInvalidArgument, errormap.CodeDecode, errormap.CodeInvalidAmount:
+		return http.StatusBadRequest
+	case errormap.CodeUnauthorized, errormap.CodeInvalidToken, errormap.CodeMissingKey:
+		return http.StatusUnauthorized
+	case errormap.CodeForbidden, errormap.CodeKeyExpired, errormap.CodeKeyInactive,
+		errormap.CodeOriginNotAllowed, errormap.CodeReadOnlyKey, errormap.CodeOutsideSchedule,
+		errormap.CodeWrongEnvironment, errormap.CodeAmountExceeded:
+		return http.StatusForbidden
+	case errormap.CodeNotFound, errormap.CodeNoRows:
+		return http.StatusNotFound
+	case errormap.CodePrecondition, errormap.DuplicateKey:
+		return http.StatusConflict
+	case errormap.CodeRateLimited, errormap.CodeQuotaExceeded, errormap.CodeTransactionLimit:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+func respondProxy(status int, body interface{}) (events.APIGatewayProxyResponse, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return events.APIGatewayProxyResponse{}, err
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(data),
+	}, nil
+}
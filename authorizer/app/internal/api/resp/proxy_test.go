@@ -0,0 +1,65 @@
+package resp
+
+import (
+	"apikey/internal/errormap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRespondError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		want   ErrorBody
+	}{
+		{
+			name:   "not found",
+			err:    fmt.Errorf("finding route: %w", errormap.ErrRouteNotFound),
+			status: http.StatusNotFound,
+			want:   ErrorBody{Code: "NOT_FOUND", Message: "route not found"},
+		},
+		{
+			name:   "unauthorized",
+			err:    errormap.ErrUnauthorized,
+			status: http.StatusUnauthorized,
+			want:   ErrorBody{Code: "UNAUTHORIZED", Message: "invalid access token"},
+		},
+		{
+			name:   "fields",
+			err:    errormap.ErrDuplicateKey.WithField("clientId", "client-1"),
+			status: http.StatusConflict,
+			want:   ErrorBody{Code: "DUPLICATE_KEY", Message: "id already exists", Fields: map[string]interface{}{"clientId": "client-1"}},
+		},
+		{
+			name:   "internal",
+			err:    errors.New("connection refused"),
+			status: http.StatusInternalServerError,
+			want:   ErrorBody{Code: "UNKNOWN", Message: "Internal Server Error"},
+		},
+	}
+
+	for _, test := range tests {
+		response, err := RespondError(test.err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != test.status {
+			t.Errorf("%s: status = %d, want %d", test.name, response.StatusCode, test.status)
+		}
+
+		var body struct {
+			Data ErrorBody `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(body.Data, test.want) {
+			t.Errorf("%s: data = %+v, want %+v", test.name, body.Data, test.want)
+		}
+	}
+}
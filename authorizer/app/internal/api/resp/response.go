@@ -1,10 +1,12 @@
 package resp
 
 import (
+	"apikey/internal/model/apikey"
+
 	"github.com/aws/aws-lambda-go/events"
 )
 
-func Respond(context map[string]interface{}, effect, clientID, resource string) (events.APIGatewayCustomAuthorizerResponse, error) {
+func Respond(context map[string]interface{}, effect, clientID, resource string, quota apikey.QuotaState) (events.APIGatewayCustomAuthorizerResponse, error) {
 	return events.APIGatewayCustomAuthorizerResponse{
 			PrincipalID: clientID,
 			PolicyDocument: events.APIGatewayCustomAuthorizerPolicy{
@@ -17,7 +19,32 @@ func Respond(context map[string]interface{}, effect, clientID, resource string)
 					},
 				},
 			},
-			Context: context, // Add additional context here
+			Context: withQuota(context, quota), // Add additional context here
 		},
 		nil
 }
+
+// withQuota adds the quota state so backends can set X-RateLimit-Limit,
+// X-RateLimit-Remaining and X-RateLimit-Reset, keys without quotas only
+// report their plan. Empty values are left out of the context.
+func withQuota(context map[string]interface{}, quota apikey.QuotaState) map[string]interface{} {
+	if context == nil {
+		context = map[string]interface{}{}
+	}
+	for key, value := range context {
+		if value == nil || value == "" {
+			delete(context, key)
+		}
+	}
+
+	if quota.Plan != "" {
+		context["plan"] = quota.Plan
+	}
+	if quota.Limit > 0 {
+		context["quotaLimit"] = quota.Limit
+		context["quotaRemaining"] = quota.Remaining
+		context["quotaResetAt"] = quota.ResetAt.Unix()
+	}
+
+	return context
+}
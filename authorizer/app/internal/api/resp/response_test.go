@@ -0,0 +1,69 @@
+package resp
+
+import (
+	"apikey/internal/model/apikey"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRespondContext(t *testing.T) {
+	resetAt := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		context map[string]interface{}
+		quota   apikey.QuotaState
+		want    map[string]interface{}
+	}{
+		{
+			name:    "plan and quota",
+			context: map[string]interface{}{"commerceId": "10"},
+			quota:   apikey.QuotaState{Plan: "gold", Limit: 100, Remaining: 40, ResetAt: resetAt},
+			want: map[string]interface{}{
+				"commerceId":     "10",
+				"plan":           "gold",
+				"quotaLimit":     int64(100),
+				"quotaRemaining": int64(40),
+				"quotaResetAt":   resetAt.Unix(),
+			},
+		},
+		{
+			name:    "exhausted quota",
+			context: nil,
+			quota:   apikey.QuotaState{Plan: "gold", Limit: 100, ResetAt: resetAt},
+			want: map[string]interface{}{
+				"plan":           "gold",
+				"quotaLimit":     int64(100),
+				"quotaRemaining": int64(0),
+				"quotaResetAt":   resetAt.Unix(),
+			},
+		},
+		{
+			name:    "plan without quota",
+			context: map[string]interface{}{},
+			quota:   apikey.QuotaState{Plan: "free"},
+			want:    map[string]interface{}{"plan": "free"},
+		},
+		{
+			name:    "empty values",
+			context: map[string]interface{}{"reasonCode": "KEY_EXPIRED", "commerceId": "", "note": nil, "testKey": false},
+			quota:   apikey.QuotaState{},
+			want:    map[string]interface{}{"reasonCode": "KEY_EXPIRED", "testKey": false},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := Respond(test.context, "Allow", "client-1", "arn:aws:execute-api:us-east-1:1:api/prod/GET/orders", test.quota)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(response.Context, test.want) {
+				t.Errorf("context = %#v, want %#v", response.Context, test.want)
+			}
+			if response.PrincipalID != "client-1" || response.PolicyDocument.Statement[0].Effect != "Allow" {
+				t.Errorf("response = %+v", response)
+			}
+		})
+	}
+}
@@ -1,24 +1,30 @@
 package server
 
 import (
+	"apikey/internal/api/resp"
+	"apikey/internal/errormap"
+	"apikey/internal/model"
+	"apikey/internal/model/health"
 	"context"
-	"fmt"
+	"encoding/json"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 )
 
+type HandlerFunc func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
 type Route struct {
 	Method  string
 	Path    string
-	Handler func(ctx context.Context, request events.APIGatewayCustomAuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error)
+	Handler HandlerFunc
 }
 
 type Router struct {
 	routes []Route
 }
 
-func (r *Router) AddRoute(method, path string, handler func(ctx context.Context, request events.APIGatewayCustomAuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error)) {
+func (r *Router) AddRoute(method, path string, handler HandlerFunc) {
 	r.routes = append(r.routes, Route{
 		Method:  method,
 		Path:    path,
@@ -26,26 +32,94 @@ func (r *Router) AddRoute(method, path string, handler func(ctx context.Context,
 	})
 }
 
-func (r *Router) FindRoute(method, path string) (*Route, bool) {
+// FindRoute returns the first route matching method and path, {name}
+// segments of the route match any segment and are returned as parameters.
+func (r *Router) FindRoute(method, path string) (*Route, map[string]string, bool) {
 
 	for _, route := range r.routes {
-		fmt.Printf("method %s : %s  path %s : %s \n", method, route.Method, path, route.Path)
+		if route.Method != method {
+			continue
+		}
+		if params, ok := matchPath(route.Path, path); ok {
+			return &route, params, true
+		}
+	}
+	return nil, nil, false
+}
+
+func matchPath(pattern, path string) (map[string]string, bool) {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternSegments) != len(pathSegments) {
+		return nil, false
+	}
 
-		if route.Method == method && strings.HasPrefix(path, route.Path) {
-			return &route, true
+	params := map[string]string{}
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params[strings.Trim(segment, "{}")] = pathSegments[i]
+			continue
+		}
+		if segment != pathSegments[i] {
+			return nil, false
 		}
 	}
-	return nil, false
+	return params, true
 }
 
-func (s *Server) Route() func(ctx context.Context, request events.APIGatewayCustomAuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
-	return func(ctx context.Context, request events.APIGatewayCustomAuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
-		router := &Router{}
+// Route dispatches the raw Lambda event: warm-up pings go to the health
+// handler, TOKEN and REQUEST authorizer events to the api key handler, API
+// Gateway proxy events to the admin routes.
+func (s *Server) Route() func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+	router := &Router{}
+
+	s.Usage(router)
+
+	return func(ctx context.Context, event json.RawMessage) (interface{}, error) {
+		defer s.flush(ctx)
+
+		var healthEvent health.Event
+		if err := json.Unmarshal(event, &healthEvent); err != nil {
+			return nil, err
+		}
+		if healthEvent.IsHealthCheck() && s.healthHandler != nil {
+			return s.healthHandler.HandleHealthCheck(ctx, healthEvent)
+		}
+
+		var request model.AuthorizerRequest
+		if err := json.Unmarshal(event, &request); err != nil {
+			return nil, err
+		}
+
+		if request.Type == model.TokenAuthorizer || request.Type == model.RequestAuthorizer {
+			return s.apikeyHandler.HandleValidateApiKey(ctx, request)
+		}
 
-		s.ApiKey(router)
+		var proxy events.APIGatewayProxyRequest
+		if err := json.Unmarshal(event, &proxy); err != nil {
+			return nil, err
+		}
 
-		route, _ := router.FindRoute("GET", "/apikey/validate")
-		return route.Handler(ctx, request)
+		route, params, ok := router.FindRoute(proxy.HTTPMethod, proxy.Path)
+		if !ok {
+			return resp.RespondError(errormap.ErrRouteNotFound)
+		}
+		if proxy.PathParameters == nil {
+			proxy.PathParameters = map[string]string{}
+		}
+		for key, value := range params {
+			if _, ok := proxy.PathParameters[key]; !ok {
+				proxy.PathParameters[key] = value
+			}
+		}
+		return route.Handler(ctx, proxy)
+	}
+}
 
+func (s *Server) flush(ctx context.Context) {
+	for _, flusher := range s.flushers {
+		if err := flusher(ctx); err != nil {
+			s.logger.WithContext(ctx).Errorf("Error flushing: %v", err)
+		}
 	}
 }
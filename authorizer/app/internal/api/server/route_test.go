@@ -0,0 +1,186 @@
+package server
+
+import (
+	"apikey/internal/model"
+	"apikey/internal/model/health"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type apikeyHandlerFunc func(ctx context.Context, request model.AuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error)
+
+func (f apikeyHandlerFunc) HandleValidateApiKey(ctx context.Context, request model.AuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+	return f(ctx, request)
+}
+
+type healthHandlerFunc func(ctx context.Context, event health.Event) (health.Report, error)
+
+func (f healthHandlerFunc) HandleHealthCheck(ctx context.Context, event health.Event) (health.Report, error) {
+	return f(ctx, event)
+}
+
+func TestRouteHealthChecks(t *testing.T) {
+	cases := map[string]string{
+		"health check":    `{"type":"HEALTH_CHECK"}`,
+		"scheduled event": `{"source":"aws.events","detail-type":"Scheduled Event","detail":{}}`,
+	}
+
+	for name, event := range cases {
+		t.Run(name, func(t *testing.T) {
+			validated, checked := false, false
+			s := New(
+				WithApiKeyHandler(apikeyHandlerFunc(func(ctx context.Context, request model.AuthorizerRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+					validated = true
+					return events.APIGatewayCustomAuthorizerResponse{}, nil
+				})),
+				WithHealthHandler(healthHandlerFunc(func(ctx context.Context, event health.Event) (health.Report, error) {
+					checked = true
+					return health.Report{Status: health.StatusOK}, nil
+				})),
+			)
+
+			result, err := s.Route()(context.Background(), json.RawMessage(event))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !checked || validated {
+				t.Errorf("checked = %v, validated = %v", checked, validated)
+			}
+			if report, ok := result.(health.Report); !ok || report.Status != health.StatusOK {
+				t.Errorf("result = %#v", result)
+			}
+		})
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		params  map[string]string
+		ok      bool
+	}{
+		{"/admin/usage/top", "/admin/usage/top", map[string]string{}, true},
+		{"/admin/usage/{clientId}", "/admin/usage/client-1", map[string]string{"clientId": "client-1"}, true},
+		{"/admin/usage/{clientId}", "/admin/usage/client-1/", map[string]string{"clientId": "client-1"}, true},
+		{"/admin/usage/{clientId}", "/admin/usage", nil, false},
+		{"/admin/usage/{clientId}", "/admin/usage/client-1/extra", nil, false},
+		{"/admin/usage/top", "/admin/usage/other", nil, false},
+	}
+	for _, test := range tests {
+		params, ok := matchPath(test.pattern, test.path)
+		if ok != test.ok {
+			t.Errorf("matchPath(%q, %q) ok = %v, want %v", test.pattern, test.path, ok, test.ok)
+			continue
+		}
+		if ok && len(params) != len(test.params) {
+			t.Errorf("matchPath(%q, %q) = %v, want %v", test.pattern, test.path, params, test.params)
+		}
+		for key, value := range test.params {
+			if params[key] != value {
+				t.Errorf("matchPath(%q, %q) = %v, want %v", test.pattern, test.path, params, test.params)
+			}
+		}
+	}
+}
+
+type usageHandlerStub struct {
+	clientID string
+}
+
+func (h *usageHandlerStub) HandleUsageSeries(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	h.clientID = request.PathParameters["clientId"]
+	return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+}
+
+func (h *usageHandlerStub) HandleTopConsumers(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+}
+
+func TestAdminRoutesRequireToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		adminToken string
+		header     string
+		status     int
+	}{
+		{"valid token", "secret", "Bearer secret", http.StatusOK},
+		{"scheme case", "secret", "bearer secret", http.StatusOK},
+		{"wrong token", "secret", "Bearer other", http.StatusUnauthorized},
+		{"token prefix", "secret", "Bearer secre", http.StatusUnauthorized},
+		{"no scheme", "secret", "secret", http.StatusUnauthorized},
+		{"no header", "secret", "", http.StatusUnauthorized},
+		{"admin disabled", "", "Bearer ", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			usage := &usageHandlerStub{}
+			s := New(WithUsageHandler(usage), WithAdminToken(test.adminToken))
+
+			headers := map[string]string{}
+			if test.header != "" {
+				headers["authorization"] = test.header
+			}
+			event, err := json.Marshal(events.APIGatewayProxyRequest{
+				HTTPMethod: "GET",
+				Path:       "/admin/usage/client-1",
+				Headers:    headers,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := s.Route()(context.Background(), event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, ok := result.(events.APIGatewayProxyResponse)
+			if !ok {
+				t.Fatalf("result = %#v", result)
+			}
+			if response.StatusCode != test.status {
+				t.Errorf("status = %d, want %d", response.StatusCode, test.status)
+			}
+			if served := usage.clientID == "client-1"; served != (test.status == http.StatusOK) {
+				t.Errorf("handler served = %v with status %d", served, response.StatusCode)
+			}
+		})
+	}
+}
+
+func TestRouteLogsFlushErrors(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	flushed := 0
+	s := New(
+		WithLogger(logger),
+		WithHealthHandler(healthHandlerFunc(func(ctx context.Context, event health.Event) (health.Report, error) {
+			return health.Report{}, nil
+		})),
+		WithFlusher(func(ctx context.Context) error {
+			flushed++
+			return errors.New("exporter unavailable")
+		}),
+		WithFlusher(func(ctx context.Context) error {
+			flushed++
+			return nil
+		}),
+	)
+
+	if _, err := s.Route()(context.Background(), json.RawMessage(`{"type":"HEALTH_CHECK"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if flushed != 2 {
+		t.Errorf("ran %d flushers, want 2", flushed)
+	}
+	if entry := hook.LastEntry(); entry == nil || entry.Level != logrus.ErrorLevel || entry.Message != "Error flushing: exporter unavailable" {
+		t.Errorf("logged %+v", hook.AllEntries())
+	}
+}
@@ -2,12 +2,24 @@ package server
 
 import (
 	"apikey/internal/api/handler"
+	"context"
+
+	"github.com/sirupsen/logrus"
 )
 
 type Server struct {
 	apikeyHandler handler.ApiKeyHandler
+	usageHandler  handler.UsageHandler
+	healthHandler handler.HealthHandler
+	adminToken    string
+	flushers      []Flusher
+	logger        *logrus.Logger
 }
 
+// Flusher sends what was buffered during an invocation before Lambda
+// freezes the environment.
+type Flusher func(ctx context.Context) error
+
 type Option func(*Server)
 
 func WithApiKeyHandler(apikeyHandler handler.ApiKeyHandler) Option {
@@ -16,8 +28,42 @@ func WithApiKeyHandler(apikeyHandler handler.ApiKeyHandler) Option {
 	}
 }
 
+func WithUsageHandler(usageHandler handler.UsageHandler) Option {
+	return func(s *Server) {
+		s.usageHandler = usageHandler
+	}
+}
+
+func WithHealthHandler(healthHandler handler.HealthHandler) Option {
+	return func(s *Server) {
+		s.healthHandler = healthHandler
+	}
+}
+
+// WithAdminToken sets the bearer token the admin routes require, they are
+// not served without one.
+func WithAdminToken(token string) Option {
+	return func(s *Server) {
+		s.adminToken = token
+	}
+}
+
+func WithFlusher(flusher Flusher) Option {
+	return func(s *Server) {
+		s.flushers = append(s.flushers, flusher)
+	}
+}
+
+// WithLogger sets the logger of the errors the server handles itself, the
+// standard logger by default.
+func WithLogger(logger *logrus.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
 func New(options ...Option) *Server {
-	s := &Server{}
+	s := &Server{logger: logrus.StandardLogger()}
 
 	for _, option := range options {
 		option(s)
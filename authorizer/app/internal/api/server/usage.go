@@ -0,0 +1,13 @@
+package server
+
+// Usage serves the usage reports of every client, only to callers holding
+// the admin token.
+func (s *Server) Usage(router *Router) {
+	if s.usageHandler == nil || s.adminToken == "" {
+		return
+	}
+
+	router.AddRoute("GET", "/admin/usage/top", s.admin(s.usageHandler.HandleTopConsumers))
+	router.AddRoute("GET", "/admin/usage/{clientId}", s.admin(s.usageHandler.HandleUsageSeries))
+
+}
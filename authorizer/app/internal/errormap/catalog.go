@@ -0,0 +1,127 @@
+package errormap
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	LocaleES      = "es"
+	LocaleEN      = "en"
+	DefaultLocale = LocaleES
+)
+
+// Locales lists the languages every reason code is translated to.
+var Locales = []string{LocaleES, LocaleEN}
+
+var catalog = map[string]map[string]string{
+	ReasonKeyNotFound: {
+		LocaleES: "API KEY inválida o inexistente",
+		LocaleEN: "Invalid or unknown API KEY",
+	},
+	ReasonMissingKey: {
+		LocaleES: "API KEY no enviada",
+		LocaleEN: "API KEY not provided",
+	},
+	ReasonKeyExpired: {
+		LocaleES: "API KEY expirado, utilize su admin para actulizar su fecha de expiracion o genera una nueva clave para seguir operando",
+		LocaleEN: "API KEY expired, use your admin to update its expiration date or generate a new key to keep operating",
+	},
+	ReasonKeyInactive: {
+		LocaleES: "API KEY desactivado, utilize su admin para reactivarlo o genera una nueva clave para seguir operando",
+		LocaleEN: "API KEY deactivated, use your admin to reactivate it or generate a new key to keep operating",
+	},
+	ReasonInvalidKeyConfig: {
+		LocaleES: "API KEY con configuracion invalida, contacte a soporte",
+		LocaleEN: "API KEY has an invalid configuration, contact support",
+	},
+	ReasonOriginNotAllowed: {
+		LocaleES: "API KEY publicable usada desde un origen no permitido",
+		LocaleEN: "Publishable API KEY used from an origin that is not allowed",
+	},
+	ReasonReadOnlyKey: {
+		LocaleES: "API KEY publicable solo permite operaciones de lectura",
+		LocaleEN: "Publishable API KEY only allows read operations",
+	},
+	ReasonOutsideSchedule: {
+		LocaleES: "API KEY fuera de su horario de acceso",
+		LocaleEN: "API KEY used outside its access schedule",
+	},
+	ReasonWrongEnvironment: {
+		LocaleES: "API KEY no valida para este ambiente",
+		LocaleEN: "API KEY is not valid for this environment",
+	},
+	ReasonRateLimited: {
+		LocaleES: "API KEY excedio su limite de solicitudes por segundo",
+		LocaleEN: "API KEY exceeded its requests per second limit",
+	},
+	ReasonQuotaExceeded: {
+		LocaleES: "API KEY excedio su limite de solicitudes del periodo",
+		LocaleEN: "API KEY exceeded its request quota for the period",
+	},
+	ReasonInvalidAmount: {
+		LocaleES: "monto de la transaccion invalido o ausente",
+		LocaleEN: "Transaction amount is invalid or missing",
+	},
+	ReasonAmountExceeded: {
+		LocaleES: "monto de la transaccion excede el maximo permitido para el comercio",
+		LocaleEN: "Transaction amount exceeds the maximum allowed for the commerce",
+	},
+	ReasonTransactionLimit: {
+		LocaleES: "comercio excedio su limite de transacciones del periodo",
+		LocaleEN: "Commerce exceeded its transaction limit for the period",
+	},
+	ReasonForbidden: {
+		LocaleES: "operacion no permitida para esta API KEY",
+		LocaleEN: "Operation not allowed for this API KEY",
+	},
+	ReasonInternalError: {
+		LocaleES: "error interno al validar la API KEY",
+		LocaleEN: "Internal error validating the API KEY",
+	},
+}
+
+// Message returns the message of reason in locale, falling back to the
+// DefaultLocale.
+func Message(reason, locale string) string {
+	messages := catalog[reason]
+	if message, ok := messages[locale]; ok {
+		return message
+	}
+	return messages[DefaultLocale]
+}
+
+// SupportedLocale returns the base language of locale ("es-MX" is "es")
+// when it is one of the Locales.
+func SupportedLocale(locale string) (string, bool) {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(locale)), "-")
+	for _, supported := range Locales {
+		if base == supported {
+			return supported, true
+		}
+	}
+	return "", false
+}
+
+// NegotiateLocale picks the supported language with the highest weight of
+// an Accept-Language header, e.g. "en-US,en;q=0.9,es;q=0.8".
+func NegotiateLocale(acceptLanguage string) (string, bool) {
+	best, bestWeight := "", 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		weight := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			w, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			weight = w
+		}
+
+		locale, ok := SupportedLocale(tag)
+		if ok && weight > bestWeight {
+			best, bestWeight = locale, weight
+		}
+	}
+	return best, best != ""
+}
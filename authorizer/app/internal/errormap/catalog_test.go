@@ -0,0 +1,49 @@
+package errormap
+
+import "testing"
+
+func TestEveryReasonCodeIsTranslated(t *testing.T) {
+	for _, reason := range ReasonCodes() {
+		messages, ok := catalog[reason]
+		if !ok {
+			t.Errorf("reason %s has no messages", reason)
+			continue
+		}
+		for _, locale := range Locales {
+			if messages[locale] == "" {
+				t.Errorf("reason %s has no %s message", reason, locale)
+			}
+		}
+	}
+}
+
+func TestCatalogOnlyHasKnownReasons(t *testing.T) {
+	known := map[string]bool{}
+	for _, reason := range ReasonCodes() {
+		known[reason] = true
+	}
+	for reason := range catalog {
+		if !known[reason] {
+			t.Errorf("catalog has messages for unknown reason %s", reason)
+		}
+	}
+}
+
+func TestNegotiateLocale(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{"en-US,en;q=0.9,es;q=0.8", LocaleEN, true},
+		{"fr-FR,es-MX;q=0.7,en;q=0.5", LocaleES, true},
+		{"fr-FR", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := NegotiateLocale(tt.header)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NegotiateLocale(%q) = (%q, %v), want (%q, %v)", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -12,8 +12,22 @@ var (
 	ErrCommerceNotFound  = errorx.NewErrorf(CodeNotFound, "company not found")
 	ErrCommerceExist     = errorx.NewErrorf(CodePrecondition, "company already exists")
 	ErrInactiveCommerce  = errorx.NewErrorf(CodeInvalidArgument, "inactive company")
-	ErrNoRows            = errorx.NewErrorf(CodeNoRows, "API KEY inválida o inexistente")
+	ErrNoRows            = errorx.NewErrorf(CodeNoRows, "no rows found")
 	ErrDuplicateKey      = errorx.NewErrorf(DuplicateKey, " id already exists")
+	ErrMissingKey        = errorx.NewErrorf(CodeMissingKey, "api key not provided")
+	ErrKeyExpired        = errorx.NewErrorf(CodeKeyExpired, "api key expired")
+	ErrKeyInactive       = errorx.NewErrorf(CodeKeyInactive, "api key inactive")
+	ErrInvalidKeyConfig  = errorx.NewErrorf(CodeInvalidKeyConfig, "api key has an invalid configuration")
+	ErrOriginNotAllowed  = errorx.NewErrorf(CodeOriginNotAllowed, "publishable api key used from a disallowed origin")
+	ErrReadOnlyKey       = errorx.NewErrorf(CodeReadOnlyKey, "publishable api key used for a write operation")
+	ErrOutsideSchedule   = errorx.NewErrorf(CodeOutsideSchedule, "api key used outside its schedule")
+	ErrWrongEnvironment  = errorx.NewErrorf(CodeWrongEnvironment, "api key used on a stage of another environment")
+	ErrRateLimited       = errorx.NewErrorf(CodeRateLimited, "api key rate limited")
+	ErrInvalidAmount     = errorx.NewErrorf(CodeInvalidAmount, "invalid or missing transaction amount")
+	ErrAmountExceeded    = errorx.NewErrorf(CodeAmountExceeded, "transaction amount over the commerce max amount")
+	ErrTransactionLimit  = errorx.NewErrorf(CodeTransactionLimit, "commerce transaction limit exceeded")
+	ErrRouteNotFound     = errorx.NewErrorf(CodeNotFound, "route not found")
+	ErrQuotaExceeded     = errorx.NewErrorf(CodeQuotaExceeded, "api key quota exceeded")
 )
 
 const (
@@ -26,4 +40,53 @@ const (
 	CodeDecode
 	CodeUnauthorized
 	DuplicateKey
+	CodeForbidden
+	CodeRateLimited
+	CodeQuotaExceeded
+	CodeMissingKey
+	CodeKeyExpired
+	CodeKeyInactive
+	CodeInvalidKeyConfig
+	CodeOriginNotAllowed
+	CodeReadOnlyKey
+	CodeOutsideSchedule
+	CodeWrongEnvironment
+	CodeInvalidAmount
+	CodeAmountExceeded
+	CodeTransactionLimit
 )
+
+// codeNames are the stable names the admin API sends instead of the codes,
+// whose values follow their declaration order.
+var codeNames = map[errorx.ErrorCode]string{
+	CodeInvalidArgument:  "INVALID_ARGUMENT",
+	CodeInvalidToken:     "INVALID_TOKEN",
+	CodeNotFound:         "NOT_FOUND",
+	CodeNoRows:           "NOT_FOUND",
+	CodePrecondition:     "PRECONDITION_FAILED",
+	CodeDecode:           "DECODE_ERROR",
+	CodeUnauthorized:     "UNAUTHORIZED",
+	DuplicateKey:         "DUPLICATE_KEY",
+	CodeForbidden:        ReasonForbidden,
+	CodeRateLimited:      ReasonRateLimited,
+	CodeQuotaExceeded:    ReasonQuotaExceeded,
+	CodeMissingKey:       ReasonMissingKey,
+	CodeKeyExpired:       ReasonKeyExpired,
+	CodeKeyInactive:      ReasonKeyInactive,
+	CodeInvalidKeyConfig: ReasonInvalidKeyConfig,
+	CodeOriginNotAllowed: ReasonOriginNotAllowed,
+	CodeReadOnlyKey:      ReasonReadOnlyKey,
+	CodeOutsideSchedule:  ReasonOutsideSchedule,
+	CodeWrongEnvironment: ReasonWrongEnvironment,
+	CodeInvalidAmount:    ReasonInvalidAmount,
+	CodeAmountExceeded:   ReasonAmountExceeded,
+	CodeTransactionLimit: ReasonTransactionLimit,
+}
+
+// CodeName returns the stable name of code, UNKNOWN when it has none.
+func CodeName(code errorx.ErrorCode) string {
+	if name, ok := codeNames[code]; ok {
+		return name
+	}
+	return "UNKNOWN"
+}
@@ -0,0 +1,37 @@
+package errormap
+
+import (
+	"apikey/pkg/errorx"
+	"errors"
+	"testing"
+)
+
+func TestSentinelsSharingACode(t *testing.T) {
+	pairs := [][2]error{
+		{ErrAuthentication, ErrUnauthorized},
+		{ErrCommerceNotFound, ErrRouteNotFound},
+		{ErrInvalidParams, ErrInvalidCommerceID},
+	}
+	for _, pair := range pairs {
+		if !errors.Is(pair[0], pair[1]) || !errors.Is(pair[1], pair[0]) {
+			t.Errorf("%q and %q do not match by code", pair[0], pair[1])
+		}
+		if errorx.Same(pair[0], pair[1]) || errorx.Same(pair[1], pair[0]) {
+			t.Errorf("%q and %q are the same sentinel", pair[0], pair[1])
+		}
+	}
+	if !errorx.Same(ErrRouteNotFound.WithField("path", "/usage"), ErrRouteNotFound) {
+		t.Error("a copy of ErrRouteNotFound is not the same sentinel")
+	}
+}
+
+func TestEveryCodeHasAName(t *testing.T) {
+	for code := CodeInvalidArgument; code <= CodeTransactionLimit; code++ {
+		if CodeName(code) == "UNKNOWN" {
+			t.Errorf("code %d has no name", code)
+		}
+	}
+	if CodeName(CodeUnknown) != "UNKNOWN" {
+		t.Errorf("CodeName(CodeUnknown) = %s", CodeName(CodeUnknown))
+	}
+}
@@ -0,0 +1,117 @@
+package errormap
+
+import (
+	"apikey/pkg/errorx"
+	"errors"
+	"net/http"
+	"sort"
+)
+
+// Reason codes are part of the authorizer contract, gateway response
+// templates and clients match on them, so existing values never change.
+const (
+	ReasonKeyNotFound      = "KEY_NOT_FOUND"
+	ReasonMissingKey       = "MISSING_KEY"
+	ReasonKeyExpired       = "KEY_EXPIRED"
+	ReasonKeyInactive      = "KEY_INACTIVE"
+	ReasonInvalidKeyConfig = "INVALID_KEY_CONFIG"
+	ReasonOriginNotAllowed = "ORIGIN_NOT_ALLOWED"
+	ReasonReadOnlyKey      = "READ_ONLY_KEY"
+	ReasonOutsideSchedule  = "OUTSIDE_SCHEDULE"
+	ReasonWrongEnvironment = "WRONG_ENVIRONMENT"
+	ReasonRateLimited      = "RATE_LIMITED"
+	ReasonQuotaExceeded    = "QUOTA_EXCEEDED"
+	ReasonInvalidAmount    = "INVALID_AMOUNT"
+	ReasonAmountExceeded   = "AMOUNT_EXCEEDED"
+	ReasonTransactionLimit = "TRANSACTION_LIMIT_EXCEEDED"
+	ReasonForbidden        = "FORBIDDEN"
+	ReasonInternalError    = "INTERNAL_ERROR"
+)
+
+var reasonCodes = map[errorx.ErrorCode]string{
+	CodeNoRows:           ReasonKeyNotFound,
+	CodeMissingKey:       ReasonMissingKey,
+	CodeKeyExpired:       ReasonKeyExpired,
+	CodeKeyInactive:      ReasonKeyInactive,
+	CodeInvalidKeyConfig: ReasonInvalidKeyConfig,
+	CodeOriginNotAllowed: ReasonOriginNotAllowed,
+	CodeReadOnlyKey:      ReasonReadOnlyKey,
+	CodeOutsideSchedule:  ReasonOutsideSchedule,
+	CodeWrongEnvironment: ReasonWrongEnvironment,
+	CodeRateLimited:      ReasonRateLimited,
+	CodeQuotaExceeded:    ReasonQuotaExceeded,
+	CodeInvalidAmount:    ReasonInvalidAmount,
+	CodeAmountExceeded:   ReasonAmountExceeded,
+	CodeTransactionLimit: ReasonTransactionLimit,
+	CodeForbidden:        ReasonForbidden,
+}
+
+// ReasonCodes lists every reason code a deny may carry.
+func ReasonCodes() []string {
+	codes := []string{ReasonInternalError}
+	for _, reason := range reasonCodes {
+		codes = append(codes, reason)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Reason returns the stable reason code of a deny and its message in
+// locale, errors without a known code are internal errors whose details
+// stay in the logs.
+func Reason(err error, locale string) (string, string) {
+	reason := ReasonInternalError
+	var errx errorx.Error
+	if errors.As(err, &errx) {
+		if code, ok := reasonCodes[errx.Code()]; ok {
+			reason = code
+		}
+	}
+	return reason, Message(reason, locale)
+}
+
+// Kind groups reason codes by how API Gateway has to answer them.
+type Kind int
+
+const (
+	// KindForbidden is a policy failure answered with a Deny (403).
+	KindForbidden Kind = iota
+	// KindUnauthorized is a missing or unknown key answered with 401.
+	KindUnauthorized
+	// KindThrottled is a quota failure, a Deny whose statusCode lets the
+	// gateway response answer 429.
+	KindThrottled
+	// KindInternal is an infrastructure failure, the authorizer fails and
+	// the gateway answers 500 instead of denying.
+	KindInternal
+)
+
+var reasonKinds = map[string]Kind{
+	ReasonKeyNotFound:      KindUnauthorized,
+	ReasonMissingKey:       KindUnauthorized,
+	ReasonRateLimited:      KindThrottled,
+	ReasonQuotaExceeded:    KindThrottled,
+	ReasonTransactionLimit: KindThrottled,
+	ReasonInternalError:    KindInternal,
+}
+
+// KindOf returns the kind of reason, policy failures by default.
+func KindOf(reason string) Kind {
+	if kind, ok := reasonKinds[reason]; ok {
+		return kind
+	}
+	return KindForbidden
+}
+
+// StatusCode is the HTTP status the gateway should answer for kind.
+func (k Kind) StatusCode() int {
+	switch k {
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindThrottled:
+		return http.StatusTooManyRequests
+	case KindInternal:
+		return http.StatusInternalServerError
+	}
+	return http.StatusForbidden
+}
@@ -2,18 +2,56 @@ package apikey
 
 import "time"
 
+type KeyType string
+
+const (
+	SecretKey      KeyType = "secret"
+	PublishableKey KeyType = "publishable"
+)
+
 type ApiKey struct {
-	PlatformData map[string]interface{} `json:"platformData"`
-	ExpiredAt    time.Time              `json:"expiredAt"`
-	IsActive     bool                   `json:"isActive"`
-	RequestCount int                    `json:"requestCount"`
-	UsageLimits  UsageLimits            `json:"usageLimits"`
-	ClientID     string                 `json:"clientId"`
+	PlatformData   map[string]interface{} `json:"platformData" bson:"platformData"`
+	ExpiredAt      time.Time              `json:"expiredAt" bson:"expiredAt"`
+	IsActive       bool                   `json:"isActive" bson:"isActive"`
+	RequestCount   int                    `json:"requestCount" bson:"requestCount"`
+	UsageLimits    UsageLimits            `json:"usageLimits" bson:"usageLimits"`
+	ClientID       string                 `json:"clientId" bson:"clientId"`
+	Type           KeyType                `json:"type" bson:"type"`
+	AllowedOrigins []string               `json:"allowedOrigins" bson:"allowedOrigins"`
+	Schedules      []AccessSchedule       `json:"schedules" bson:"schedules"`
+	Environment    Environment            `json:"environment" bson:"environment"`
+	Stages         []string               `json:"stages" bson:"stages"`
+	CompanyID      int64                  `json:"companyId" bson:"companyId"`
+	Timezone       string                 `json:"timezone" bson:"timezone"`
+	SchemaVersion  int                    `json:"-" bson:"schemaVersion"`
+	Quotas         []Quota                `json:"-" bson:"-"`
+	Locale         string                 `json:"-" bson:"-"`
+	FailMode       string                 `json:"-" bson:"-"`
+	Staleness      time.Duration          `json:"-" bson:"-"`
 }
 
 type ApiKeys []ApiKey
 
 type UsageLimits struct {
-	DailyLimit int `json:"dailyLimit"`
-	Limit      int `json:"limit"`
+	Plan          string  `json:"plan" bson:"plan"`
+	DailyLimit    int     `json:"dailyLimit" bson:"dailyLimit"`
+	MonthlyLimit  int     `json:"monthlyLimit" bson:"monthlyLimit"`
+	Limit         int     `json:"limit" bson:"limit"`
+	RatePerSecond float64 `json:"ratePerSecond" bson:"ratePerSecond"`
+	Burst         int     `json:"burst" bson:"burst"`
+}
+
+// Access describes the call the key is presented for. Route holds the
+// resource template when RouteTemplate is set, TOKEN events only carry the
+// requested path.
+type Access struct {
+	ApiKey        string
+	Method        string
+	Stage         string
+	Route         string
+	RouteTemplate bool
+	Origin        string
+	Referer       string
+	Payment       bool
+	Amount        string
 }
@@ -0,0 +1,12 @@
+package apikey
+
+import "strconv"
+
+// CommerceID is the commerce whose rule applies to the key's payments, the
+// key's company. Keys without a company have none.
+func (r *ApiKey) CommerceID() string {
+	if r.CompanyID == 0 {
+		return ""
+	}
+	return strconv.FormatInt(r.CompanyID, 10)
+}
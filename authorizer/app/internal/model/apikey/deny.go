@@ -0,0 +1,16 @@
+package apikey
+
+// DenyError is returned when the key is valid but the call is not allowed
+// right now, Context is added to the authorizer context of the Deny.
+type DenyError struct {
+	Err     error
+	Context map[string]interface{}
+}
+
+func (e *DenyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DenyError) Unwrap() error {
+	return e.Err
+}
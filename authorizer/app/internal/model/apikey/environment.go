@@ -0,0 +1,46 @@
+package apikey
+
+import "strings"
+
+type Environment string
+
+const (
+	TestEnvironment Environment = "test"
+	LiveEnvironment Environment = "live"
+)
+
+// environmentStages are the API stages each environment may call when the
+// key does not list its own Stages.
+var environmentStages = map[Environment][]string{
+	TestEnvironment: {"sandbox", "dev"},
+	LiveEnvironment: {"prod"},
+}
+
+// IsTestKey reports whether the key belongs to the test environment.
+func (r *ApiKey) IsTestKey() bool {
+	return r.Environment == TestEnvironment
+}
+
+// AllowsStage reports whether the key may call the API stage. Keys list
+// their stages explicitly or get the ones of their environment, keys with
+// neither are not restricted. Restricted keys are denied when the stage is
+// unknown, e.g. when the method arn could not be parsed.
+func (r *ApiKey) AllowsStage(stage string) bool {
+	stages := r.Stages
+	if len(stages) == 0 {
+		stages = environmentStages[r.Environment]
+	}
+	if len(stages) == 0 {
+		return true
+	}
+	if stage == "" {
+		return false
+	}
+
+	for _, allowed := range stages {
+		if strings.EqualFold(allowed, stage) {
+			return true
+		}
+	}
+	return false
+}
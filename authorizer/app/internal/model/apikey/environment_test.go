@@ -0,0 +1,38 @@
+package apikey
+
+import "testing"
+
+func TestAllowsStage(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   ApiKey
+		stage string
+		want  bool
+	}{
+		{"test key on sandbox", ApiKey{Environment: TestEnvironment}, "sandbox", true},
+		{"test key on dev", ApiKey{Environment: TestEnvironment}, "DEV", true},
+		{"test key on prod", ApiKey{Environment: TestEnvironment}, "prod", false},
+		{"live key on prod", ApiKey{Environment: LiveEnvironment}, "prod", true},
+		{"live key on sandbox", ApiKey{Environment: LiveEnvironment}, "sandbox", false},
+		{"own stages", ApiKey{Environment: TestEnvironment, Stages: []string{"qa"}}, "qa", true},
+		{"own stages replace the environment ones", ApiKey{Environment: TestEnvironment, Stages: []string{"qa"}}, "sandbox", false},
+		{"unrestricted key", ApiKey{}, "anything", true},
+		{"unrestricted key on unknown stage", ApiKey{}, "", true},
+		{"test key on unknown stage", ApiKey{Environment: TestEnvironment}, "", false},
+		{"own stages on unknown stage", ApiKey{Stages: []string{"qa"}}, "", false},
+	}
+	for _, test := range tests {
+		if got := test.key.AllowsStage(test.stage); got != test.want {
+			t.Errorf("%s: AllowsStage(%q) = %v, want %v", test.name, test.stage, got, test.want)
+		}
+	}
+}
+
+func TestIsTestKey(t *testing.T) {
+	if !(&ApiKey{Environment: TestEnvironment}).IsTestKey() {
+		t.Error("expected a test key")
+	}
+	if (&ApiKey{Environment: LiveEnvironment}).IsTestKey() {
+		t.Error("expected a live key")
+	}
+}
@@ -0,0 +1,18 @@
+package apikey
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Fingerprint identifies an api key in logs and local files without
+// exposing it.
+func Fingerprint(apiKey string) string {
+	return Hash(apiKey)[:16]
+}
+
+// Hash is what the database stores and looks up instead of the api key.
+func Hash(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
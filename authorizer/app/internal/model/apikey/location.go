@@ -0,0 +1,23 @@
+package apikey
+
+import (
+	"sync"
+	"time"
+)
+
+var locations sync.Map
+
+// LoadLocation is time.LoadLocation cached by name, so every timezone is read
+// from the zoneinfo database once per container instead of once per call.
+func LoadLocation(name string) (*time.Location, error) {
+	if location, ok := locations.Load(name); ok {
+		return location.(*time.Location), nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, location)
+	return location, nil
+}
@@ -0,0 +1,90 @@
+package apikey
+
+import (
+	"net/url"
+	"strings"
+)
+
+var readOnlyMethods = []string{"GET", "HEAD", "OPTIONS"}
+
+// IsPublishable reports whether the key is meant to be embedded in browsers.
+func (r *ApiKey) IsPublishable() bool {
+	return r.Type == PublishableKey
+}
+
+// AllowsMethod reports whether the key entitles the method,
+// publishable keys are read only.
+func (r *ApiKey) AllowsMethod(method string) bool {
+	if !r.IsPublishable() {
+		return true
+	}
+	for _, allowed := range readOnlyMethods {
+		if strings.EqualFold(method, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsOrigin reports whether the origin, or the referer when no origin
+// was sent, matches one of the AllowedOrigins. Entries may be a full origin
+// (https://shop.example.com), a host (shop.example.com) or a wildcard
+// subdomain (https://*.example.com, *.example.com). Entries without a scheme
+// only allow https and entries without a port allow any port.
+func (r *ApiKey) AllowsOrigin(origin, referer string) bool {
+	if !r.IsPublishable() {
+		return true
+	}
+
+	u, ok := parseOrigin(origin)
+	if !ok {
+		u, ok = parseOrigin(referer)
+	}
+	if !ok {
+		return false
+	}
+
+	for _, allowed := range r.AllowedOrigins {
+		if matchOrigin(allowed, u) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseOrigin(value string) (*url.URL, bool) {
+	if value == "" || value == "null" {
+		return nil, false
+	}
+	u, err := url.Parse(strings.ToLower(value))
+	if err != nil || u.Scheme == "" || u.Hostname() == "" {
+		return nil, false
+	}
+	return u, true
+}
+
+func matchOrigin(allowed string, u *url.URL) bool {
+	allowed = strings.ToLower(strings.TrimSpace(allowed))
+	if allowed == "" {
+		return false
+	}
+
+	scheme := "https"
+	if before, after, ok := strings.Cut(allowed, "://"); ok {
+		scheme, allowed = before, after
+	}
+	if u.Scheme != scheme {
+		return false
+	}
+	allowed = strings.TrimSuffix(allowed, "/")
+
+	host := u.Hostname()
+	if strings.Contains(allowed, ":") {
+		host = u.Host
+	}
+
+	if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == allowed
+}
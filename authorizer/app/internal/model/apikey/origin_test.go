@@ -0,0 +1,62 @@
+package apikey
+
+import "testing"
+
+func TestAllowsOrigin(t *testing.T) {
+	key := ApiKey{
+		Type:           PublishableKey,
+		AllowedOrigins: []string{"shop.example.com", "https://*.example.org", "http://localhost:3000", "https://admin.example.net:8443"},
+	}
+
+	tests := []struct {
+		origin  string
+		referer string
+		want    bool
+	}{
+		{"https://shop.example.com", "", true},
+		{"https://SHOP.example.com", "", true},
+		{"https://shop.example.com:8443", "", true},
+		{"http://shop.example.com", "", false},
+		{"https://evil-shop.example.com", "", false},
+		{"https://shop.example.com.evil.com", "", false},
+		{"https://a.example.org", "", true},
+		{"https://a.b.example.org", "", true},
+		{"https://example.org", "", false},
+		{"http://a.example.org", "", false},
+		{"http://localhost:3000", "", true},
+		{"http://localhost:4000", "", false},
+		{"https://localhost:3000", "", false},
+		{"https://admin.example.net:8443", "", true},
+		{"https://admin.example.net", "", false},
+		{"", "https://shop.example.com/cart?id=1", true},
+		{"null", "https://shop.example.com/", true},
+		{"", "", false},
+		{"shop.example.com", "", false},
+	}
+	for _, test := range tests {
+		if got := key.AllowsOrigin(test.origin, test.referer); got != test.want {
+			t.Errorf("AllowsOrigin(%q, %q) = %v, want %v", test.origin, test.referer, got, test.want)
+		}
+	}
+}
+
+func TestAllowsOriginSecretKey(t *testing.T) {
+	key := ApiKey{Type: SecretKey}
+	if !key.AllowsOrigin("", "") {
+		t.Error("expected secret keys to allow any origin")
+	}
+}
+
+func TestAllowsMethod(t *testing.T) {
+	publishable := ApiKey{Type: PublishableKey}
+	for method, want := range map[string]bool{"GET": true, "head": true, "OPTIONS": true, "POST": false, "DELETE": false} {
+		if got := publishable.AllowsMethod(method); got != want {
+			t.Errorf("AllowsMethod(%q) = %v, want %v", method, got, want)
+		}
+	}
+
+	secret := ApiKey{Type: SecretKey}
+	if !secret.AllowsMethod("POST") {
+		t.Error("expected secret keys to allow any method")
+	}
+}
@@ -0,0 +1,31 @@
+package apikey
+
+// PlanPolicy softens the quotas of a plan: an alert is raised when usage
+// crosses each of the Thresholds, in percent of the limit, and calls keep
+// being allowed until usage is Overage percent above the limit.
+type PlanPolicy struct {
+	Thresholds []int `json:"thresholds"`
+	Overage    int   `json:"overage"`
+}
+
+// HardLimit is the usage at which calls are denied.
+func (p PlanPolicy) HardLimit(limit int64) int64 {
+	return limit + limit*int64(p.Overage)/100
+}
+
+// Crossed returns the thresholds reached by the call that took the usage
+// to used, since counters grow one call at a time each threshold is
+// crossed by exactly one call per window.
+func (p PlanPolicy) Crossed(limit, used int64) []int {
+	var crossed []int
+	for _, threshold := range p.Thresholds {
+		at := (limit*int64(threshold) + 99) / 100
+		if at < 1 {
+			at = 1
+		}
+		if used == at {
+			crossed = append(crossed, threshold)
+		}
+	}
+	return crossed
+}
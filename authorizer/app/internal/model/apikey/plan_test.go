@@ -0,0 +1,56 @@
+package apikey
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanPolicyHardLimit(t *testing.T) {
+	tests := []struct {
+		overage int
+		limit   int64
+		want    int64
+	}{
+		{0, 100, 100},
+		{10, 100, 110},
+		{10, 5, 5},
+		{50, 5, 7},
+		{100, 3, 6},
+		{10, 0, 0},
+	}
+	for _, test := range tests {
+		if got := (PlanPolicy{Overage: test.overage}).HardLimit(test.limit); got != test.want {
+			t.Errorf("HardLimit(%d) with %d%% overage = %d, want %d", test.limit, test.overage, got, test.want)
+		}
+	}
+}
+
+func TestPlanPolicyCrossed(t *testing.T) {
+	policy := PlanPolicy{Thresholds: []int{50, 80, 100}}
+	tests := []struct {
+		limit int64
+		used  int64
+		want  []int
+	}{
+		{10, 4, nil},
+		{10, 5, []int{50}},
+		{10, 6, nil},
+		{10, 8, []int{80}},
+		{10, 10, []int{100}},
+		{10, 11, nil},
+		// Thresholds round up, 80% of 3 is reached by the third call.
+		{3, 2, []int{50}},
+		{3, 3, []int{80, 100}},
+		// A threshold below one call is reached by the first one.
+		{1, 1, []int{50, 80, 100}},
+	}
+	for _, test := range tests {
+		if got := policy.Crossed(test.limit, test.used); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Crossed(%d, %d) = %v, want %v", test.limit, test.used, got, test.want)
+		}
+	}
+
+	if got := (PlanPolicy{}).Crossed(10, 10); got != nil {
+		t.Errorf("Crossed without thresholds = %v, want none", got)
+	}
+}
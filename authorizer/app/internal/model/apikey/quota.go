@@ -0,0 +1,119 @@
+package apikey
+
+import "time"
+
+type Period string
+
+const (
+	DailyPeriod   Period = "daily"
+	MonthlyPeriod Period = "monthly"
+)
+
+// QuotaWindow is the span a quota counts calls in, it starts and ends at
+// local midnight so a day may last 23 or 25 hours across DST changes.
+type QuotaWindow struct {
+	Period Period
+	Start  time.Time
+	End    time.Time
+}
+
+// WindowAt returns the window of period that contains t in location.
+func WindowAt(period Period, t time.Time, location *time.Location) QuotaWindow {
+	local := t.In(location)
+	year, month, day := local.Date()
+
+	window := QuotaWindow{Period: period}
+	switch period {
+	case MonthlyPeriod:
+		window.Start = midnight(year, month, 1, location)
+		window.End = midnight(year, month+1, 1, location)
+	default:
+		window.Start = midnight(year, month, day, location)
+		window.End = midnight(year, month, day+1, location)
+	}
+	return window
+}
+
+// midnight returns the first instant of the day. Where DST starts at
+// midnight, e.g. America/Santiago, time.Date resolves the missing midnight
+// to the day before, the day then starts when the new offset takes effect.
+func midnight(year int, month time.Month, day int, location *time.Location) time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, location)
+	if _, _, d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Date(); t.Day() != d {
+		_, t = t.ZoneBounds()
+	}
+	return t
+}
+
+// Quota is the state of one of the key's limits in its current window.
+type Quota struct {
+	QuotaWindow
+	Limit int64
+	Used  int64
+}
+
+// QuotaLimit is a limit the key has configured for a period.
+type QuotaLimit struct {
+	Period Period
+	Limit  int64
+}
+
+// QuotaLimits returns the configured limits, longest period first.
+func (r *ApiKey) QuotaLimits() []QuotaLimit {
+	var limits []QuotaLimit
+	if r.UsageLimits.MonthlyLimit > 0 {
+		limits = append(limits, QuotaLimit{Period: MonthlyPeriod, Limit: int64(r.UsageLimits.MonthlyLimit)})
+	}
+	if r.UsageLimits.DailyLimit > 0 {
+		limits = append(limits, QuotaLimit{Period: DailyPeriod, Limit: int64(r.UsageLimits.DailyLimit)})
+	}
+	return limits
+}
+
+// QuotaContext exposes the bounds of each quota window to the authorizer
+// context, e.g. dailyWindowStart and dailyWindowEnd.
+func QuotaContext(quotas []Quota) map[string]interface{} {
+	context := map[string]interface{}{}
+	for _, quota := range quotas {
+		context[string(quota.Period)+"WindowStart"] = quota.Start.Format(time.RFC3339)
+		context[string(quota.Period)+"WindowEnd"] = quota.End.Format(time.RFC3339)
+	}
+	return context
+}
+
+// QuotaState is what backends need to answer X-RateLimit-* headers.
+type QuotaState struct {
+	Plan      string
+	Limit     int64
+	Remaining int64
+	ResetAt   time.Time
+}
+
+// QuotaState reports the plan and the quota closest to exhaustion, the one
+// resetting first on ties. Limit and ResetAt are zero without quotas.
+func (r *ApiKey) QuotaState() QuotaState {
+	state := QuotaState{Plan: r.UsageLimits.Plan}
+
+	var binding *Quota
+	for i := range r.Quotas {
+		quota := &r.Quotas[i]
+		if binding == nil || quota.remaining() < binding.remaining() ||
+			(quota.remaining() == binding.remaining() && quota.End.Before(binding.End)) {
+			binding = quota
+		}
+	}
+	if binding != nil {
+		state.Limit = binding.Limit
+		state.Remaining = binding.remaining()
+		state.ResetAt = binding.End
+	}
+
+	return state
+}
+
+func (q *Quota) remaining() int64 {
+	if q.Used >= q.Limit {
+		return 0
+	}
+	return q.Limit - q.Used
+}
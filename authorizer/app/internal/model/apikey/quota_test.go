@@ -0,0 +1,75 @@
+package apikey
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowAt(t *testing.T) {
+	santiago, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		period   Period
+		at       string
+		location *time.Location
+		start    string
+		end      string
+	}{
+		{"utc day", DailyPeriod, "2024-06-03T10:00:00Z", time.UTC, "2024-06-03T00:00:00Z", "2024-06-04T00:00:00Z"},
+		{"utc midnight", DailyPeriod, "2024-06-04T00:00:00Z", time.UTC, "2024-06-04T00:00:00Z", "2024-06-05T00:00:00Z"},
+		{"utc year end", DailyPeriod, "2024-12-31T23:59:59Z", time.UTC, "2024-12-31T00:00:00Z", "2025-01-01T00:00:00Z"},
+		{"utc month", MonthlyPeriod, "2024-02-29T12:00:00Z", time.UTC, "2024-02-01T00:00:00Z", "2024-03-01T00:00:00Z"},
+		{"utc december", MonthlyPeriod, "2024-12-15T12:00:00Z", time.UTC, "2024-12-01T00:00:00Z", "2025-01-01T00:00:00Z"},
+		// 23:30 on June 2 in Santiago (-04) is already June 3 in UTC.
+		{"local day before utc", DailyPeriod, "2024-06-03T03:30:00Z", santiago, "2024-06-02T04:00:00Z", "2024-06-03T04:00:00Z"},
+		{"local midnight", DailyPeriod, "2024-06-03T04:00:00Z", santiago, "2024-06-03T04:00:00Z", "2024-06-04T04:00:00Z"},
+		// DST ends at midnight of 2024-04-07, April 6 lasts 25 hours.
+		{"dst end long day", DailyPeriod, "2024-04-06T15:00:00Z", santiago, "2024-04-06T03:00:00Z", "2024-04-07T04:00:00Z"},
+		{"dst end repeated hour", DailyPeriod, "2024-04-07T03:30:00Z", santiago, "2024-04-06T03:00:00Z", "2024-04-07T04:00:00Z"},
+		{"dst end next day", DailyPeriod, "2024-04-07T04:00:00Z", santiago, "2024-04-07T04:00:00Z", "2024-04-08T04:00:00Z"},
+		// DST starts at midnight of 2024-09-08, which does not exist, the day
+		// starts at 01:00 and lasts 23 hours.
+		{"dst start day before", DailyPeriod, "2024-09-08T03:30:00Z", santiago, "2024-09-07T04:00:00Z", "2024-09-08T04:00:00Z"},
+		{"dst start short day", DailyPeriod, "2024-09-08T04:00:00Z", santiago, "2024-09-08T04:00:00Z", "2024-09-09T03:00:00Z"},
+		{"dst start next day", DailyPeriod, "2024-09-09T03:00:00Z", santiago, "2024-09-09T03:00:00Z", "2024-09-10T03:00:00Z"},
+		{"dst end month", MonthlyPeriod, "2024-04-15T12:00:00Z", santiago, "2024-04-01T03:00:00Z", "2024-05-01T04:00:00Z"},
+		{"dst start month", MonthlyPeriod, "2024-09-15T12:00:00Z", santiago, "2024-09-01T04:00:00Z", "2024-10-01T03:00:00Z"},
+		// 23:30 on April 30 in Santiago is already May 1 in UTC.
+		{"local month before utc", MonthlyPeriod, "2024-05-01T03:30:00Z", santiago, "2024-04-01T03:00:00Z", "2024-05-01T04:00:00Z"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			at := utc(test.at)
+			window := WindowAt(test.period, at, test.location)
+			if !window.Start.Equal(utc(test.start)) || !window.End.Equal(utc(test.end)) {
+				t.Errorf("window = [%v, %v), want [%s, %s)", window.Start.UTC(), window.End.UTC(), test.start, test.end)
+			}
+			if at.Before(window.Start) || !at.Before(window.End) {
+				t.Errorf("%s is outside its window [%v, %v)", test.at, window.Start.UTC(), window.End.UTC())
+			}
+		})
+	}
+}
+
+func TestWindowsAreContiguous(t *testing.T) {
+	santiago, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := utc("2024-01-01T12:00:00Z")
+	for _, period := range []Period{DailyPeriod, MonthlyPeriod} {
+		window := WindowAt(period, at, santiago)
+		for i := 0; i < 400; i++ {
+			next := WindowAt(period, window.End, santiago)
+			if !next.Start.Equal(window.End) {
+				t.Fatalf("%s window after [%v, %v) starts at %v", period, window.Start, window.End, next.Start)
+			}
+			window = next
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package apikey
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	scheduleTimeLayout = "15:04"
+	scheduleDateLayout = "2006-01-02"
+	// scheduleLookahead bounds the search of the next opening.
+	scheduleLookahead = 366
+)
+
+// AccessSchedule is a recurring window in which the key may be used,
+// e.g. 01:00 to 05:00 America/Mexico_City on weekdays. An End before Start
+// closes the window the next day and an End equal to Start spans the whole
+// day. Exceptions are dates (2006-01-02) in which the window does not open.
+type AccessSchedule struct {
+	Timezone   string   `json:"timezone" bson:"timezone"`
+	Days       []string `json:"days" bson:"days"`
+	Start      string   `json:"start" bson:"start"`
+	End        string   `json:"end" bson:"end"`
+	Exceptions []string `json:"exceptions" bson:"exceptions"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+type window struct {
+	location   *time.Location
+	days       map[time.Weekday]bool
+	start      time.Duration
+	end        time.Duration
+	overnight  bool
+	exceptions map[string]bool
+}
+
+func (s AccessSchedule) parse() (*window, error) {
+	location, err := LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule timezone %q: %w", s.Timezone, err)
+	}
+
+	start, err := parseClock(s.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(s.End)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &window{
+		location:   location,
+		start:      start,
+		end:        end,
+		overnight:  end <= start,
+		exceptions: map[string]bool{},
+	}
+
+	if len(s.Days) > 0 {
+		w.days = map[time.Weekday]bool{}
+		for _, day := range s.Days {
+			weekday, ok := weekdays[strings.ToLower(day)]
+			if !ok {
+				return nil, fmt.Errorf("invalid schedule day %q", day)
+			}
+			w.days[weekday] = true
+		}
+	}
+
+	for _, date := range s.Exceptions {
+		if _, err := time.Parse(scheduleDateLayout, date); err != nil {
+			return nil, fmt.Errorf("invalid schedule exception %q: %w", date, err)
+		}
+		w.exceptions[date] = true
+	}
+
+	return w, nil
+}
+
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse(scheduleTimeLayout, value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schedule time %q: %w", value, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// bounds returns when the window opens and closes for the day of t in the
+// schedule's timezone and whether it opens at all that day. Both are wall
+// clock times so the window keeps its local hours across DST changes.
+func (w *window) bounds(t time.Time) (time.Time, time.Time, bool) {
+	year, month, day := t.Date()
+	date := time.Date(year, month, day, 0, 0, 0, 0, w.location)
+	if w.days != nil && !w.days[date.Weekday()] {
+		return time.Time{}, time.Time{}, false
+	}
+	if w.exceptions[date.Format(scheduleDateLayout)] {
+		return time.Time{}, time.Time{}, false
+	}
+
+	closingDay := day
+	if w.overnight {
+		closingDay++
+	}
+	opening := w.at(year, month, day, w.start)
+	closing := w.at(year, month, closingDay, w.end)
+	return opening, closing, true
+}
+
+func (w *window) at(year int, month time.Month, day int, clock time.Duration) time.Time {
+	hours, minutes := int(clock/time.Hour), int(clock%time.Hour/time.Minute)
+	return time.Date(year, month, day, hours, minutes, 0, 0, w.location)
+}
+
+// isOpen checks the window opened today and the one opened yesterday,
+// which is still open at t when it spans midnight.
+func (w *window) isOpen(t time.Time) bool {
+	local := t.In(w.location)
+	for _, day := range []time.Time{local.AddDate(0, 0, -1), local} {
+		opening, closing, ok := w.bounds(day)
+		if ok && !t.Before(opening) && t.Before(closing) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *window) next(t time.Time) (time.Time, bool) {
+	local := t.In(w.location)
+	for i := 0; i <= scheduleLookahead; i++ {
+		opening, _, ok := w.bounds(local.AddDate(0, 0, i))
+		if ok && opening.After(t) {
+			return opening, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// OpenAt reports whether the key schedules allow calls at t, keys without
+// schedules are always open. When closed it returns the next time any of
+// the schedules opens, zero if none opens within a year.
+func (r *ApiKey) OpenAt(t time.Time) (bool, time.Time, error) {
+	if len(r.Schedules) == 0 {
+		return true, time.Time{}, nil
+	}
+
+	var next time.Time
+	for _, schedule := range r.Schedules {
+		w, err := schedule.parse()
+		if err != nil {
+			return false, time.Time{}, err
+		}
+		if w.isOpen(t) {
+			return true, time.Time{}, nil
+		}
+		if opening, ok := w.next(t); ok && (next.IsZero() || opening.Before(next)) {
+			next = opening
+		}
+	}
+
+	return false, next, nil
+}
@@ -0,0 +1,99 @@
+package apikey
+
+import (
+	"testing"
+	"time"
+)
+
+func utc(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestOpenAt(t *testing.T) {
+	weekdays := AccessSchedule{Timezone: "UTC", Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00"}
+	overnight := AccessSchedule{Timezone: "UTC", Days: []string{"friday"}, Start: "22:00", End: "02:00"}
+	holidays := AccessSchedule{Timezone: "UTC", Start: "22:00", End: "02:00", Exceptions: []string{"2024-12-24"}}
+	newYork := AccessSchedule{Timezone: "America/New_York", Start: "01:00", End: "05:00"}
+
+	tests := []struct {
+		name      string
+		schedules []AccessSchedule
+		at        string
+		open      bool
+		next      string
+	}{
+		{"no schedules", nil, "2024-06-03T10:00:00Z", true, ""},
+		{"weekday inside", []AccessSchedule{weekdays}, "2024-06-03T10:00:00Z", true, ""},
+		{"weekday at start", []AccessSchedule{weekdays}, "2024-06-03T09:00:00Z", true, ""},
+		{"weekday at end", []AccessSchedule{weekdays}, "2024-06-03T17:00:00Z", false, "2024-06-04T09:00:00Z"},
+		{"weekend", []AccessSchedule{weekdays}, "2024-06-08T10:00:00Z", false, "2024-06-10T09:00:00Z"},
+		{"overnight same day", []AccessSchedule{overnight}, "2024-06-07T23:00:00Z", true, ""},
+		{"overnight next day", []AccessSchedule{overnight}, "2024-06-08T01:59:00Z", true, ""},
+		{"overnight closed", []AccessSchedule{overnight}, "2024-06-08T02:00:00Z", false, "2024-06-14T22:00:00Z"},
+		{"overnight not opened that day", []AccessSchedule{overnight}, "2024-06-08T23:00:00Z", false, "2024-06-14T22:00:00Z"},
+		{"exception day", []AccessSchedule{holidays}, "2024-12-24T23:00:00Z", false, "2024-12-25T22:00:00Z"},
+		{"exception spills over midnight", []AccessSchedule{holidays}, "2024-12-25T01:00:00Z", false, "2024-12-25T22:00:00Z"},
+		{"day after exception", []AccessSchedule{holidays}, "2024-12-26T01:00:00Z", true, ""},
+		{"whole day", []AccessSchedule{{Timezone: "UTC", Start: "00:00", End: "00:00"}}, "2024-06-03T12:00:00Z", true, ""},
+		{"earliest next of many", []AccessSchedule{weekdays, overnight}, "2024-06-07T18:00:00Z", false, "2024-06-07T22:00:00Z"},
+		// 01:00 is 06:00 UTC in winter and 05:00 UTC in summer.
+		{"dst winter open", []AccessSchedule{newYork}, "2024-01-15T06:30:00Z", true, ""},
+		{"dst winter before", []AccessSchedule{newYork}, "2024-01-15T05:30:00Z", false, "2024-01-15T06:00:00Z"},
+		{"dst summer open", []AccessSchedule{newYork}, "2024-07-15T05:30:00Z", true, ""},
+		{"dst summer after", []AccessSchedule{newYork}, "2024-07-15T09:00:00Z", false, "2024-07-16T05:00:00Z"},
+		// On 2024-03-10 clocks skip from 02:00 to 03:00, the window lasts 3 hours.
+		{"dst spring forward open", []AccessSchedule{newYork}, "2024-03-10T08:30:00Z", true, ""},
+		{"dst spring forward closed", []AccessSchedule{newYork}, "2024-03-10T09:00:00Z", false, "2024-03-11T05:00:00Z"},
+		// On 2024-11-03 clocks go back from 02:00 to 01:00, the window lasts 5 hours.
+		{"dst fall back open", []AccessSchedule{newYork}, "2024-11-03T09:30:00Z", true, ""},
+		{"dst fall back closed", []AccessSchedule{newYork}, "2024-11-03T10:00:00Z", false, "2024-11-04T06:00:00Z"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key := ApiKey{Schedules: test.schedules}
+			open, next, err := key.OpenAt(utc(test.at))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if open != test.open {
+				t.Errorf("expected open %v, got %v", test.open, open)
+			}
+			if test.next == "" && !next.IsZero() {
+				t.Errorf("expected no next opening, got %v", next)
+			}
+			if test.next != "" && !next.Equal(utc(test.next)) {
+				t.Errorf("expected next opening %s, got %v", test.next, next.UTC())
+			}
+		})
+	}
+}
+
+func TestOpenAtInvalidSchedule(t *testing.T) {
+	schedules := []AccessSchedule{
+		{Timezone: "Mars/Olympus", Start: "09:00", End: "17:00"},
+		{Timezone: "UTC", Start: "9am", End: "17:00"},
+		{Timezone: "UTC", Start: "09:00", End: "17:00", Days: []string{"someday"}},
+		{Timezone: "UTC", Start: "09:00", End: "17:00", Exceptions: []string{"24/12/2024"}},
+	}
+	for _, schedule := range schedules {
+		key := ApiKey{Schedules: []AccessSchedule{schedule}}
+		if _, _, err := key.OpenAt(utc("2024-06-03T10:00:00Z")); err == nil {
+			t.Errorf("expected an error for %+v", schedule)
+		}
+	}
+}
+
+func TestValidateExpiration(t *testing.T) {
+	now := utc("2024-06-03T10:00:00Z")
+	key := ApiKey{IsActive: true, ExpiredAt: now.Add(time.Minute)}
+	if err := key.Validate(now); err != nil {
+		t.Errorf("expected a valid key, got %v", err)
+	}
+	if err := key.Validate(now.Add(time.Hour)); err == nil {
+		t.Error("expected the key to be expired an hour later")
+	}
+}
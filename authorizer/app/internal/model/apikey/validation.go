@@ -1,6 +1,7 @@
 package apikey
 
 import (
+	"apikey/internal/errormap"
 	"regexp"
 	"time"
 
@@ -16,13 +17,14 @@ const (
 	mailMaxLength = 100
 )
 
-func ExpiredAtRule() []validation.Rule {
+// ExpiredAtRule fails when the expiration is before now.
+func ExpiredAtRule(now time.Time) []validation.Rule {
 	return []validation.Rule{
 		validation.Required,
 		validation.By(func(value interface{}) error {
 			if val, ok := value.(time.Time); ok {
-				if val.Before(time.Now()) {
-					return validation.NewError("validation_expired", "API KEY expirado, utilize su admin para actulizar su fecha de expiracion o genera una nueva clave para seguir operando")
+				if val.Before(now) {
+					return validation.NewError("validation_expired", errormap.Message(errormap.ReasonKeyExpired, errormap.DefaultLocale))
 				}
 			}
 			return nil
@@ -39,7 +41,7 @@ func DescriptionRules() []validation.Rule {
 
 func ActiveRules() []validation.Rule {
 	return []validation.Rule{
-		validation.Required.Error("API KEY desactivado, utilize su admin para reactivarlo o genera una nueva clave para seguir operando"),
+		validation.Required.Error(errormap.Message(errormap.ReasonKeyInactive, errormap.DefaultLocale)),
 	}
 }
 
@@ -70,12 +72,26 @@ func CompanyIdRules() []validation.Rule {
 	}
 }
 
-func (r *ApiKey) Validate() error {
+// Validate checks the key can be used at now, an inactive key is reported
+// before an expired one. A key without expiration was stored incomplete,
+// it is an invalid configuration rather than an expired key.
+func (r *ApiKey) Validate(now time.Time) error {
 	err := validation.ValidateStruct(r,
-		validation.Field(&r.ExpiredAt, ExpiredAtRule()...),
+		validation.Field(&r.ExpiredAt, ExpiredAtRule(now)...),
 		validation.Field(&r.IsActive, ActiveRules()...),
 	)
 	if err != nil {
+		if errs, ok := err.(validation.Errors); ok {
+			if errs["isActive"] != nil {
+				return errormap.ErrKeyInactive
+			}
+			if errs["expiredAt"] != nil && r.ExpiredAt.IsZero() {
+				return errormap.ErrInvalidKeyConfig
+			}
+			if errs["expiredAt"] != nil {
+				return errormap.ErrKeyExpired
+			}
+		}
 		return err
 	}
 
@@ -0,0 +1,29 @@
+package apikey
+
+import (
+	"apikey/internal/errormap"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		key  ApiKey
+		want error
+	}{
+		{"valid", ApiKey{IsActive: true, ExpiredAt: now.Add(time.Second)}, nil},
+		{"expired", ApiKey{IsActive: true, ExpiredAt: now.Add(-time.Second)}, errormap.ErrKeyExpired},
+		{"inactive", ApiKey{ExpiredAt: now.Add(time.Hour)}, errormap.ErrKeyInactive},
+		{"inactive and expired", ApiKey{ExpiredAt: now.Add(-time.Hour)}, errormap.ErrKeyInactive},
+		{"without expiration", ApiKey{IsActive: true}, errormap.ErrInvalidKeyConfig},
+	}
+	for _, test := range tests {
+		err := test.key.Validate(now)
+		if !errors.Is(err, test.want) || (test.want == nil && err != nil) {
+			t.Errorf("%s: Validate = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
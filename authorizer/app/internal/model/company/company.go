@@ -10,6 +10,8 @@ type Company struct {
 	Phone       string    `json:"phone"`
 	Mail        string    `json:"mail"`
 	Rfc         string    `json:"rfc"`
+	Timezone    string    `json:"timezone"`
+	Locale      string    `json:"locale"`
 	Created     time.Time `json:"created"`
 	Updated     time.Time `json:"updated"`
 }
@@ -0,0 +1,38 @@
+package health
+
+import "apikey/pkg/buildinfo"
+
+const (
+	// Type is the type of the health check events sent by warm-up rules.
+	Type = "HEALTH_CHECK"
+
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+)
+
+// Event is a warm-up ping: an event of type HEALTH_CHECK or an EventBridge
+// scheduled event.
+type Event struct {
+	Type       string `json:"type"`
+	Source     string `json:"source"`
+	DetailType string `json:"detail-type"`
+}
+
+func (e Event) IsHealthCheck() bool {
+	return e.Type == Type || (e.Source == "aws.events" && e.DetailType == "Scheduled Event")
+}
+
+// Report answers a health check.
+type Report struct {
+	Status        string         `json:"status"`
+	Database      Database       `json:"database"`
+	Cache         map[string]int `json:"cache"`
+	ConfigVersion string         `json:"configVersion"`
+	Build         buildinfo.Info `json:"build"`
+}
+
+type Database struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
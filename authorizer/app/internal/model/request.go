@@ -1,5 +1,17 @@
 package model
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	TokenAuthorizer   = "TOKEN"
+	RequestAuthorizer = "REQUEST"
+)
+
 type AppSyncEvent struct {
 	Operation      string            `json:"operation"`
 	Input          string            `json:"input"`
@@ -9,3 +21,56 @@ type AppSyncEvent struct {
 type GetByCompany struct {
 	Id int64 `json:"id"`
 }
+
+// AuthorizerRequest holds both TOKEN and REQUEST authorizer events,
+// TOKEN events only fill Type, MethodArn and AuthorizationToken.
+type AuthorizerRequest struct {
+	events.APIGatewayCustomAuthorizerRequestTypeRequest
+	AuthorizationToken string `json:"authorizationToken"`
+}
+
+// Header returns the value of the named header ignoring its case.
+func (r AuthorizerRequest) Header(name string) string {
+	for key, value := range r.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// MethodArn is the parsed form of
+// arn:aws:execute-api:{region}:{account}:{apiId}/{stage}/{method}/{resource}.
+type MethodArn struct {
+	Region    string
+	AccountID string
+	ApiID     string
+	Stage     string
+	Method    string
+	Resource  string
+}
+
+func ParseMethodArn(arn string) (MethodArn, error) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[2] != "execute-api" {
+		return MethodArn{}, fmt.Errorf("invalid method arn: %s", arn)
+	}
+
+	path := strings.SplitN(parts[5], "/", 4)
+	if len(path) < 3 {
+		return MethodArn{}, fmt.Errorf("invalid method arn: %s", arn)
+	}
+
+	methodArn := MethodArn{
+		Region:    parts[3],
+		AccountID: parts[4],
+		ApiID:     path[0],
+		Stage:     path[1],
+		Method:    path[2],
+	}
+	if len(path) == 4 {
+		methodArn.Resource = path[3]
+	}
+
+	return methodArn, nil
+}
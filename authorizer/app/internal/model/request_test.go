@@ -0,0 +1,47 @@
+package model
+
+import "testing"
+
+func TestParseMethodArn(t *testing.T) {
+	tests := []struct {
+		arn  string
+		want MethodArn
+		ok   bool
+	}{
+		{
+			arn:  "arn:aws:execute-api:us-east-1:123456789012:abc123/prod/GET/orders/123",
+			want: MethodArn{Region: "us-east-1", AccountID: "123456789012", ApiID: "abc123", Stage: "prod", Method: "GET", Resource: "orders/123"},
+			ok:   true,
+		},
+		{
+			arn:  "arn:aws:execute-api:us-east-1:123456789012:abc123/test/POST",
+			want: MethodArn{Region: "us-east-1", AccountID: "123456789012", ApiID: "abc123", Stage: "test", Method: "POST"},
+			ok:   true,
+		},
+		{arn: "arn:aws:execute-api:us-east-1:123456789012:abc123/prod"},
+		{arn: "arn:aws:lambda:us-east-1:123456789012:function/prod/GET/orders"},
+		{arn: "invalid"},
+		{arn: ""},
+	}
+	for _, test := range tests {
+		got, err := ParseMethodArn(test.arn)
+		if (err == nil) != test.ok {
+			t.Errorf("ParseMethodArn(%q) error = %v, want ok %v", test.arn, err, test.ok)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseMethodArn(%q) = %+v, want %+v", test.arn, got, test.want)
+		}
+	}
+}
+
+func TestHeader(t *testing.T) {
+	var request AuthorizerRequest
+	request.Headers = map[string]string{"X-Api-Key": "key"}
+	if got := request.Header("x-api-key"); got != "key" {
+		t.Errorf("expected header key, got %q", got)
+	}
+	if got := request.Header("origin"); got != "" {
+		t.Errorf("expected no origin, got %q", got)
+	}
+}
@@ -0,0 +1,39 @@
+package usage
+
+import "time"
+
+const DayLayout = "2006-01-02"
+
+// UntemplatedRoute stands for the resource of calls whose event carries no
+// resource template, TOKEN events only have the requested path and a route
+// per path would make a document per resource id.
+const UntemplatedRoute = "*"
+
+// Usage counts the calls a key made to a route in a day, days are in the
+// timezone the key's quotas reset in.
+type Usage struct {
+	ClientID  string    `json:"clientId" bson:"clientId"`
+	Day       string    `json:"day" bson:"day"`
+	Route     string    `json:"route" bson:"route"`
+	Count     int64     `json:"count" bson:"count"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+type Usages []Usage
+
+// Point is the usage of a key in a day, total and per route.
+type Point struct {
+	Day    string           `json:"day"`
+	Count  int64            `json:"count"`
+	Routes map[string]int64 `json:"routes"`
+}
+
+type Series []Point
+
+// Consumer is the usage of a key over a date range.
+type Consumer struct {
+	ClientID string `json:"clientId" bson:"_id"`
+	Count    int64  `json:"count" bson:"count"`
+}
+
+type Consumers []Consumer
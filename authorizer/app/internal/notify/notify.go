@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/sirupsen/logrus"
+)
+
+const QuotaThreshold = "QuotaThreshold"
+
+// Event tells that a key crossed a soft threshold of one of its quotas.
+type Event struct {
+	Type        string    `json:"type"`
+	ClientID    string    `json:"clientId"`
+	Plan        string    `json:"plan"`
+	Period      string    `json:"period"`
+	Threshold   int       `json:"threshold"`
+	Limit       int64     `json:"limit"`
+	Used        int64     `json:"used"`
+	WindowStart time.Time `json:"windowStart"`
+	WindowEnd   time.Time `json:"windowEnd"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+type logNotifier struct {
+	logger *logrus.Logger
+}
+
+// NewLogNotifier writes events to the log, for deployments without a bus.
+func NewLogNotifier(logger *logrus.Logger) Notifier {
+	return &logNotifier{logger: logger}
+}
+
+func (n *logNotifier) Notify(ctx context.Context, event Event) error {
+	n.logger.WithFields(logrus.Fields{
+		"clientId":  event.ClientID,
+		"plan":      event.Plan,
+		"period":    event.Period,
+		"threshold": event.Threshold,
+		"limit":     event.Limit,
+		"used":      event.Used,
+	}).Warnf("%s: apikey %s reached %d%% of its %s quota", event.Type, event.ClientID, event.Threshold, event.Period)
+	return nil
+}
+
+// EventBridgeAPI is the part of the EventBridge client used here.
+type EventBridgeAPI interface {
+	PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error)
+}
+
+type eventBridgeNotifier struct {
+	client EventBridgeAPI
+	bus    string
+	source string
+}
+
+// NewEventBridgeNotifier puts events on bus with the event Type as
+// detail-type so rules can route them to email, Slack or a CRM.
+func NewEventBridgeNotifier(client EventBridgeAPI, bus, source string) Notifier {
+	return &eventBridgeNotifier{
+		client: client,
+		bus:    bus,
+		source: source,
+	}
+}
+
+func (n *eventBridgeNotifier) Notify(ctx context.Context, event Event) error {
+	detail, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	out, err := n.client.PutEvents(ctx, &eventbridge.PutEventsInput{
+		Entries: []types.PutEventsRequestEntry{{
+			EventBusName: aws.String(n.bus),
+			Source:       aws.String(n.source),
+			DetailType:   aws.String(event.Type),
+			Detail:       aws.String(string(detail)),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	if out.FailedEntryCount > 0 {
+		return &putEventsError{entry: out.Entries[0]}
+	}
+	return nil
+}
+
+type putEventsError struct {
+	entry types.PutEventsResultEntry
+}
+
+func (e *putEventsError) Error() string {
+	return "put events failed: " + aws.ToString(e.entry.ErrorCode) + " " + aws.ToString(e.entry.ErrorMessage)
+}
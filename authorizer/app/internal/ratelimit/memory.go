@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"apikey/pkg/clock"
+	"context"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often expired buckets and counters are dropped.
+const pruneInterval = time.Minute
+
+type bucket struct {
+	tokens    float64
+	last      time.Time
+	expiresAt time.Time
+}
+
+type count struct {
+	value     int64
+	expiresAt time.Time
+}
+
+// memoryLimiter keeps the buckets and counters in the Lambda container,
+// each warm container enforces the limits on its own. Full buckets and
+// counters of past windows are pruned as calls come in.
+type memoryLimiter struct {
+	mu        sync.Mutex
+	clock     clock.Clock
+	buckets   map[string]*bucket
+	counts    map[string]*count
+	nextPrune time.Time
+}
+
+func newMemoryLimiter(clock clock.Clock) *memoryLimiter {
+	return &memoryLimiter{
+		clock:   clock,
+		buckets: map[string]*bucket{},
+		counts:  map[string]*count{},
+	}
+}
+
+func NewMemoryLimiter(clock clock.Clock) Limiter {
+	return newMemoryLimiter(clock)
+}
+
+func NewMemoryCounter(clock clock.Clock) Counter {
+	return newMemoryLimiter(clock)
+}
+
+func (m *memoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := m.clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit.capacity(), last: now}
+		m.buckets[key] = b
+	}
+
+	tokens := refill(limit, b.tokens, b.last, now)
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	b.tokens, b.last, b.expiresAt = tokens, now, now.Add(limit.ttl())
+
+	return result(limit, tokens, allowed), nil
+}
+
+func (m *memoryLimiter) Increment(ctx context.Context, key string, limit int64, expiresAt time.Time) (int64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(m.clock.Now())
+
+	c := m.count(key)
+	if c == nil {
+		c = &count{expiresAt: expiresAt}
+		m.counts[key] = c
+	}
+	if limit > 0 && c.value >= limit {
+		return c.value, false, nil
+	}
+	c.value++
+
+	return c.value, true, nil
+}
+
+func (m *memoryLimiter) Decrement(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c := m.count(key); c != nil && c.value > 0 {
+		c.value--
+	}
+	return nil
+}
+
+func (m *memoryLimiter) Get(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c := m.count(key); c != nil {
+		return c.value, nil
+	}
+	return 0, nil
+}
+
+// count returns the live counter of key, dropping it once expired.
+func (m *memoryLimiter) count(key string) *count {
+	c, ok := m.counts[key]
+	if !ok {
+		return nil
+	}
+	if !m.clock.Now().Before(c.expiresAt) {
+		delete(m.counts, key)
+		return nil
+	}
+	return c
+}
+
+// prune drops the buckets that refilled and the counters that expired, at
+// most once per pruneInterval. Keys of counters include their window so
+// they are never read again once it ends.
+func (m *memoryLimiter) prune(now time.Time) {
+	if now.Before(m.nextPrune) {
+		return
+	}
+	m.nextPrune = now.Add(pruneInterval)
+
+	for key, b := range m.buckets {
+		if !now.Before(b.expiresAt) {
+			delete(m.buckets, key)
+		}
+	}
+	for key, c := range m.counts {
+		if !now.Before(c.expiresAt) {
+			delete(m.counts, key)
+		}
+	}
+}
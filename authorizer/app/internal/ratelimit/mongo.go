@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"apikey/pkg/clock"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoLimiter keeps buckets and counters as documents keyed by _id, a TTL
+// index on expiresAt removes them once they are no longer needed.
+type mongoLimiter struct {
+	collection *mongo.Collection
+	clock      clock.Clock
+}
+
+func newMongoLimiter(db *mongo.Client, dbName, collectionName string, clock clock.Clock) *mongoLimiter {
+	return &mongoLimiter{
+		collection: db.Database(dbName).Collection(collectionName),
+		clock:      clock,
+	}
+}
+
+func NewMongoLimiter(db *mongo.Client, dbName, collectionName string, clock clock.Clock) Limiter {
+	return newMongoLimiter(db, dbName, collectionName, clock)
+}
+
+func NewMongoCounter(db *mongo.Client, dbName, collectionName string, clock clock.Clock) Counter {
+	return newMongoLimiter(db, dbName, collectionName, clock)
+}
+
+type bucketDocument struct {
+	Tokens  float64 `bson:"tokens"`
+	Allowed bool    `bson:"allowed"`
+}
+
+// Allow refills and takes the token with an update pipeline so the whole
+// step is a single atomic write.
+func (m *mongoLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := m.clock.Now()
+	capacity := limit.capacity()
+
+	elapsed := bson.M{"$divide": bson.A{
+		bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$last", now}}}}}},
+		1000,
+	}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$min": bson.A{capacity, bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$tokens", capacity}},
+				bson.M{"$multiply": bson.A{elapsed, limit.Rate}},
+			}}}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"allowed": bson.M{"$gte": bson.A{"$tokens", 1}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"tokens":    bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"last":      now,
+			"expiresAt": now.Add(limit.ttl()),
+		}}},
+	}
+
+	var doc bucketDocument
+	err := m.collection.FindOneAndUpdate(ctx, bson.M{"_id": "rate:" + key}, pipeline,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&doc)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result(limit, doc.Tokens, doc.Allowed), nil
+}
+
+type countDocument struct {
+	Count int64 `bson:"count"`
+}
+
+// Increment only matches the document while it is under the limit, once
+// reached the upsert collides with the existing _id and nothing is counted.
+func (m *mongoLimiter) Increment(ctx context.Context, key string, limit int64, expiresAt time.Time) (int64, bool, error) {
+	filter := bson.M{"_id": "count:" + key}
+	if limit > 0 {
+		filter["count"] = bson.M{"$lt": limit}
+	}
+	update := bson.M{
+		"$inc":         bson.M{"count": 1},
+		"$setOnInsert": bson.M{"expiresAt": expiresAt},
+	}
+
+	var doc countDocument
+	err := m.collection.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&doc)
+	if mongo.IsDuplicateKeyError(err) {
+		count, err := m.Get(ctx, key)
+		return count, false, err
+	} else if err != nil {
+		return 0, false, err
+	}
+
+	return doc.Count, true, nil
+}
+
+func (m *mongoLimiter) Decrement(ctx context.Context, key string) error {
+	_, err := m.collection.UpdateOne(ctx,
+		bson.M{"_id": "count:" + key, "count": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"count": -1}},
+	)
+	return err
+}
+
+func (m *mongoLimiter) Get(ctx context.Context, key string) (int64, error) {
+	var doc countDocument
+	err := m.collection.FindOne(ctx, bson.M{"_id": "count:" + key}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	return doc.Count, err
+}
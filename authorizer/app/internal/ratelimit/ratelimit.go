@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second that holds at
+// most Burst tokens, every call takes one token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.Rate > 0
+}
+
+// capacity defaults the burst to one second of sustained rate.
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return math.Max(1, math.Ceil(l.Rate))
+}
+
+// ttl is how long a bucket takes to fill up, after that it can be dropped.
+func (l Limit) ttl() time.Duration {
+	return time.Duration(math.Ceil(l.capacity()/l.Rate*float64(time.Second))) + time.Second
+}
+
+// Result is the outcome of taking a token, RetryAfter tells when the next
+// token will be available when the call was not allowed.
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Limiter takes tokens from the bucket identified by key.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Counter counts calls per key in fixed windows, e.g. a daily quota.
+type Counter interface {
+	// Increment adds one to key unless it already reached limit, a limit of
+	// zero means no limit. The key expires at expiresAt. It returns the
+	// count and whether it was incremented.
+	Increment(ctx context.Context, key string, limit int64, expiresAt time.Time) (int64, bool, error)
+	// Decrement gives back a call counted by Increment, e.g. when a later
+	// check denied it. Counts never go below zero.
+	Decrement(ctx context.Context, key string) error
+	// Get returns the count of key, zero when it does not exist.
+	Get(ctx context.Context, key string) (int64, error)
+}
+
+// refill returns the tokens of a bucket that held tokens at last.
+func refill(limit Limit, tokens float64, last, now time.Time) float64 {
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens += elapsed * limit.Rate
+	}
+	return math.Min(limit.capacity(), tokens)
+}
+
+// result describes a take that left tokens in the bucket.
+func result(limit Limit, tokens float64, allowed bool) Result {
+	if !allowed {
+		wait := (1 - tokens) / limit.Rate
+		return Result{
+			RetryAfter: time.Duration(math.Ceil(wait * float64(time.Second))),
+		}
+	}
+	return Result{
+		Allowed:   true,
+		Remaining: int(tokens),
+	}
+}
@@ -0,0 +1,230 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type manualClock struct {
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	return c.now
+}
+
+type backend struct {
+	name    string
+	limiter Limiter
+	counter Counter
+}
+
+func backends(t *testing.T, clock *manualClock) []backend {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	backends := []backend{
+		{"memory", NewMemoryLimiter(clock), NewMemoryCounter(clock)},
+		{"redis", NewRedisLimiter(client, clock), NewRedisCounter(client, clock)},
+	}
+	if mongoClient := mongoTestClient(t); mongoClient != nil {
+		dbName := fmt.Sprintf("ratelimit_test_%d", time.Now().UnixNano())
+		t.Cleanup(func() { mongoClient.Database(dbName).Drop(context.Background()) })
+		backends = append(backends, backend{"mongo",
+			NewMongoLimiter(mongoClient, dbName, "api_key_limits", clock),
+			NewMongoCounter(mongoClient, dbName, "api_key_limits", clock),
+		})
+	}
+	return backends
+}
+
+// mongoTestClient connects to USER_VAR_TEST_MONGO_URI, nil when it is not
+// set so the Mongo backend is only exercised where a database is available.
+func mongoTestClient(t *testing.T) *mongo.Client {
+	uri := os.Getenv("USER_VAR_TEST_MONGO_URI")
+	if uri == "" {
+		return nil
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(ctx) })
+	return client
+}
+
+func TestLimiterBurstAndRefill(t *testing.T) {
+	ctx := context.Background()
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	limit := Limit{Rate: 2, Burst: 3}
+
+	for _, b := range backends(t, clock) {
+		t.Run(b.name, func(t *testing.T) {
+			key := "client-" + b.name
+			for i := 0; i < 3; i++ {
+				result, err := b.limiter.Allow(ctx, key, limit)
+				if err != nil {
+					t.Fatalf("allow %d: %v", i, err)
+				}
+				if !result.Allowed {
+					t.Fatalf("call %d within burst was rejected", i)
+				}
+			}
+
+			result, err := b.limiter.Allow(ctx, key, limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Allowed {
+				t.Fatal("call over burst was allowed")
+			}
+			if result.RetryAfter != 500*time.Millisecond {
+				t.Errorf("retry after = %v, want 500ms", result.RetryAfter)
+			}
+
+			clock.now = clock.now.Add(500 * time.Millisecond)
+			result, err = b.limiter.Allow(ctx, key, limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.Allowed {
+				t.Error("call after refill was rejected")
+			}
+		})
+	}
+}
+
+func TestCounterStopsAtLimit(t *testing.T) {
+	ctx := context.Background()
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	expiresAt := clock.now.Add(time.Hour)
+
+	for _, b := range backends(t, clock) {
+		t.Run(b.name, func(t *testing.T) {
+			key := "daily-" + b.name
+			for i := int64(1); i <= 2; i++ {
+				count, counted, err := b.counter.Increment(ctx, key, 2, expiresAt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !counted || count != i {
+					t.Fatalf("increment %d = (%d, %v)", i, count, counted)
+				}
+			}
+
+			count, counted, err := b.counter.Increment(ctx, key, 2, expiresAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if counted || count != 2 {
+				t.Errorf("increment over limit = (%d, %v), want (2, false)", count, counted)
+			}
+
+			count, err = b.counter.Get(ctx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 2 {
+				t.Errorf("get = %d, want 2", count)
+			}
+		})
+	}
+}
+
+func TestMemoryPrunesExpiredEntries(t *testing.T) {
+	ctx := context.Background()
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	m := newMemoryLimiter(clock)
+
+	if _, err := m.Allow(ctx, "old-client", Limit{Rate: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.Increment(ctx, "daily:old-client:2026-10-18", 0, clock.now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.now = clock.now.Add(2 * time.Hour)
+	if _, err := m.Allow(ctx, "new-client", Limit{Rate: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := m.buckets["old-client"]; ok {
+		t.Error("expected the refilled bucket to be pruned")
+	}
+	if _, ok := m.counts["daily:old-client:2026-10-18"]; ok {
+		t.Error("expected the expired counter to be pruned")
+	}
+	if _, ok := m.buckets["new-client"]; !ok {
+		t.Error("expected the new bucket to be kept")
+	}
+}
+
+func TestMemoryKeepsLiveEntries(t *testing.T) {
+	ctx := context.Background()
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	m := newMemoryLimiter(clock)
+
+	if _, _, err := m.Increment(ctx, "daily:client:2026-10-18", 0, clock.now.Add(12*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	clock.now = clock.now.Add(2 * time.Hour)
+	count, _, err := m.Increment(ctx, "daily:client:2026-10-18", 0, clock.now.Add(10*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected the live counter to be kept, got %d", count)
+	}
+}
+
+func TestCounterDecrement(t *testing.T) {
+	ctx := context.Background()
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	expiresAt := clock.now.Add(time.Hour)
+
+	for _, b := range backends(t, clock) {
+		t.Run(b.name, func(t *testing.T) {
+			key := "release-" + b.name
+			if err := b.counter.Decrement(ctx, key); err != nil {
+				t.Fatalf("decrement of a missing counter: %v", err)
+			}
+			for i := 0; i < 2; i++ {
+				if _, _, err := b.counter.Increment(ctx, key, 2, expiresAt); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i := 0; i < 3; i++ {
+				if err := b.counter.Decrement(ctx, key); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			count, err := b.counter.Get(ctx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 0 {
+				t.Errorf("get = %d, want 0", count)
+			}
+
+			count, counted, err := b.counter.Increment(ctx, key, 2, expiresAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !counted || count != 1 {
+				t.Errorf("increment after release = (%d, %v), want (1, true)", count, counted)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"apikey/pkg/clock"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript refills and takes a token in one step so concurrent
+// containers never share a token.
+//
+//	KEYS[1] bucket, ARGV rate, capacity, now (ms), ttl (ms)
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(bucket[1]) or capacity
+local last = tonumber(bucket[2]) or now
+if now > last then
+	tokens = tokens + (now - last) / 1000 * rate
+end
+tokens = math.min(capacity, tokens)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', now)
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`)
+
+// counterScript increments unless the limit was reached.
+//
+//	KEYS[1] counter, ARGV limit, ttl (ms)
+var counterScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+if limit > 0 and current >= limit then
+	return {current, 0}
+end
+current = redis.call('INCR', KEYS[1])
+if current == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return {current, 1}
+`)
+
+// decrementScript decrements unless the counter is missing or zero, DECR
+// keeps the expiry of the key.
+//
+//	KEYS[1] counter
+var decrementScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+if current > 0 then
+	current = redis.call('DECR', KEYS[1])
+end
+return current
+`)
+
+type redisLimiter struct {
+	client redis.UniversalClient
+	clock  clock.Clock
+	prefix string
+}
+
+func newRedisLimiter(client redis.UniversalClient, clock clock.Clock) *redisLimiter {
+	return &redisLimiter{
+		client: client,
+		clock:  clock,
+		prefix: "apikey:",
+	}
+}
+
+func NewRedisLimiter(client redis.UniversalClient, clock clock.Clock) Limiter {
+	return newRedisLimiter(client, clock)
+}
+
+func NewRedisCounter(client redis.UniversalClient, clock clock.Clock) Counter {
+	return newRedisLimiter(client, clock)
+}
+
+func (r *redisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := r.clock.Now().UnixMilli()
+	values, err := tokenBucketScript.Run(ctx, r.client, []string{r.prefix + "rate:" + key},
+		limit.Rate, limit.capacity(), now, limit.ttl().Milliseconds()).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed, _ := values[0].(int64)
+	tokens, err := strconv.ParseFloat(values[1].(string), 64)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result(limit, tokens, allowed == 1), nil
+}
+
+// Increment expires the counter relative to the clock rather than at an
+// absolute time, so clock skew against the Redis server does not matter.
+func (r *redisLimiter) Increment(ctx context.Context, key string, limit int64, expiresAt time.Time) (int64, bool, error) {
+	ttl := expiresAt.Sub(r.clock.Now())
+	if ttl < time.Millisecond {
+		ttl = time.Millisecond
+	}
+	values, err := counterScript.Run(ctx, r.client, []string{r.prefix + "count:" + key},
+		limit, ttl.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, false, err
+	}
+	return values[0], values[1] == 1, nil
+}
+
+func (r *redisLimiter) Decrement(ctx context.Context, key string) error {
+	return decrementScript.Run(ctx, r.client, []string{r.prefix + "count:" + key}).Err()
+}
+
+func (r *redisLimiter) Get(ctx context.Context, key string) (int64, error) {
+	value, err := r.client.Get(ctx, r.prefix+"count:"+key).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return value, err
+}
@@ -27,18 +27,28 @@ func NewApiKeyRepository(logger *logrus.Logger, db *mongo.Client, dbName, collec
 	}
 }
 
+// ValidateApiKey finds the key by its hash, or by the key itself while
+// its document is below schema version 3.
 func (u *apikeyRepository) ValidateApiKey(ctx context.Context, apiKey string) (*apikey.ApiKey, error) {
-	var dbApiKey apikey.ApiKey
-	filter := bson.M{"apiKey": apiKey}
+	filter := bson.M{"$or": bson.A{
+		bson.M{"apiKeyHash": apikey.Hash(apiKey)},
+		bson.M{"apiKey": apiKey},
+	}}
 
-	err := u.collection.FindOne(ctx, filter).Decode(&dbApiKey)
+	raw, err := u.collection.FindOne(ctx, filter).Raw()
 	if err == mongo.ErrNoDocuments {
-		u.logger.Infof("ApiKey  %s not found", apiKey)
+		u.logger.WithContext(ctx).Infof("ApiKey %s not found", apikey.Fingerprint(apiKey))
 		return nil, errormap.ErrNoRows
 	} else if err != nil {
-		u.logger.Errorf("error retrieving ApiKey : %v", err)
+		u.logger.WithContext(ctx).Errorf("error retrieving ApiKey : %v", err)
 		return nil, err
 	}
 
-	return &dbApiKey, nil
+	dbApiKey, err := DecodeApiKey(raw)
+	if err != nil {
+		u.logger.WithContext(ctx).Errorf("error retrieving ApiKey : %v", err)
+		return nil, err
+	}
+
+	return dbApiKey, nil
 }
@@ -0,0 +1,196 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Database and collections of the authorizer.
+const (
+	Database                = "api_key_db"
+	ApiKeyCollection        = "api_key"
+	CompanyCollection       = "company"
+	UsageCollection         = "api_key_usage"
+	CommerceRuleCollection  = "commerce_rules"
+	LimitCollection         = "api_key_limits"
+	SchemaVersionCollection = "schema_version"
+)
+
+// SchemaVersion is the version of the collections and documents this code
+// expects. Version 2 added the bson mapping of api keys, version 3 stores
+// the hash of the api key instead of the key.
+const SchemaVersion = 3
+
+// schemaVersionID is the _id of the document holding the schema version.
+const schemaVersionID = "apikey"
+
+// Returned when an index exists with other options or other keys.
+const (
+	indexOptionsConflict  = 85
+	indexKeySpecsConflict = 86
+)
+
+var ErrSchemaTooNew = errors.New("database schema is newer than this build")
+
+type BootstrapOptions struct {
+	// Database is the database to bootstrap, Database by default.
+	Database string
+	// UsageRetention is how long daily usage is kept after its last call.
+	UsageRetention time.Duration
+}
+
+type collectionIndex struct {
+	collection string
+	model      mongo.IndexModel
+}
+
+// Bootstrap creates the indexes the repositories rely on and records the
+// schema version once no document is left to migrate. It is idempotent,
+// indexes that exist are left as they are, TTL indexes are updated to the
+// configured retention and indexes whose keys changed are rebuilt. It fails
+// with ErrSchemaTooNew when the database was bootstrapped by a newer build.
+func Bootstrap(ctx context.Context, logger *logrus.Logger, client *mongo.Client, opts BootstrapOptions) error {
+	if opts.Database == "" {
+		opts.Database = Database
+	}
+	db := client.Database(opts.Database)
+
+	version, err := ReadSchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("%w: database at version %d, build at %d", ErrSchemaTooNew, version, SchemaVersion)
+	}
+
+	indexes := []collectionIndex{
+		// Keys are looked up by hash, documents below version 3 by the key
+		// until they are migrated. The unique indexes leave out documents
+		// without the field instead of colliding on null.
+		{ApiKeyCollection, mongo.IndexModel{
+			Keys: bson.D{{Key: "apiKeyHash", Value: 1}},
+			Options: options.Index().SetName("uk_apiKeyHash").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"apiKeyHash": bson.M{"$type": "string"}}),
+		}},
+		{ApiKeyCollection, mongo.IndexModel{
+			Keys: bson.D{{Key: "apiKey", Value: 1}},
+			Options: options.Index().SetName("uk_apiKey").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"apiKey": bson.M{"$type": "string"}}),
+		}},
+		// Documents below version 2 store it as clientid.
+		{ApiKeyCollection, mongo.IndexModel{
+			Keys: bson.D{{Key: "clientId", Value: 1}},
+			Options: options.Index().SetName("uk_clientId").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"clientId": bson.M{"$type": "string"}}),
+		}},
+		{CompanyCollection, mongo.IndexModel{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetName("idx_id"),
+		}},
+		{CommerceRuleCollection, mongo.IndexModel{
+			Keys:    bson.D{{Key: "id_commerce", Value: 1}},
+			Options: options.Index().SetName("idx_id_commerce"),
+		}},
+		{UsageCollection, mongo.IndexModel{
+			Keys:    bson.D{{Key: "clientId", Value: 1}, {Key: "day", Value: 1}, {Key: "route", Value: 1}},
+			Options: options.Index().SetName("uk_clientId_day_route").SetUnique(true),
+		}},
+		{UsageCollection, mongo.IndexModel{
+			Keys:    bson.D{{Key: "day", Value: 1}},
+			Options: options.Index().SetName("idx_day"),
+		}},
+		{UsageCollection, mongo.IndexModel{
+			Keys:    bson.D{{Key: "updatedAt", Value: 1}},
+			Options: options.Index().SetName("ttl_updatedAt").SetExpireAfterSeconds(int32(opts.UsageRetention.Seconds())),
+		}},
+		{LimitCollection, mongo.IndexModel{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetName("ttl_expiresAt").SetExpireAfterSeconds(0),
+		}},
+	}
+
+	for _, index := range indexes {
+		if err := ensureIndex(ctx, db, index); err != nil {
+			return err
+		}
+		logger.WithContext(ctx).Infof("Index %s.%s ready", index.collection, *index.model.Options.Name)
+	}
+
+	if version < SchemaVersion {
+		pending, err := db.Collection(ApiKeyCollection).CountDocuments(ctx, outdatedApiKeys())
+		if err != nil {
+			return fmt.Errorf("error counting outdated api keys : %w", err)
+		}
+		if pending > 0 {
+			logger.WithContext(ctx).Warnf("%d api keys below schema version %d, run the migration", pending, SchemaVersion)
+			return nil
+		}
+		if err := writeSchemaVersion(ctx, db, SchemaVersion); err != nil {
+			return err
+		}
+		logger.WithContext(ctx).Infof("Schema version %d recorded", SchemaVersion)
+	}
+
+	return nil
+}
+
+// ensureIndex creates the index, updates the expiry of an existing TTL index
+// whose retention changed and rebuilds any other index that changed.
+func ensureIndex(ctx context.Context, db *mongo.Database, index collectionIndex) error {
+	collection := db.Collection(index.collection)
+	_, err := collection.Indexes().CreateOne(ctx, index.model)
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == indexOptionsConflict && index.model.Options.ExpireAfterSeconds != nil {
+		err = db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: index.collection},
+			{Key: "index", Value: bson.D{
+				{Key: "name", Value: *index.model.Options.Name},
+				{Key: "expireAfterSeconds", Value: *index.model.Options.ExpireAfterSeconds},
+			}},
+		}).Err()
+	} else if errors.As(err, &cmdErr) && (cmdErr.Code == indexOptionsConflict || cmdErr.Code == indexKeySpecsConflict) {
+		if _, err = collection.Indexes().DropOne(ctx, *index.model.Options.Name); err == nil {
+			_, err = collection.Indexes().CreateOne(ctx, index.model)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("error creating index %s.%s : %w", index.collection, *index.model.Options.Name, err)
+	}
+	return nil
+}
+
+// ReadSchemaVersion returns the version the database was bootstrapped at,
+// 0 when it never was.
+func ReadSchemaVersion(ctx context.Context, db *mongo.Database) (int, error) {
+	var doc struct {
+		Version int `bson:"version"`
+	}
+	err := db.Collection(SchemaVersionCollection).
+		FindOne(ctx, bson.M{"_id": schemaVersionID}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("error reading schema version : %w", err)
+	}
+	return doc.Version, nil
+}
+
+func writeSchemaVersion(ctx context.Context, db *mongo.Database, version int) error {
+	_, err := db.Collection(SchemaVersionCollection).UpdateOne(ctx,
+		bson.M{"_id": schemaVersionID},
+		bson.M{"$set": bson.M{"version": version, "updatedAt": time.Now().UTC()}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("error writing schema version : %w", err)
+	}
+	return nil
+}
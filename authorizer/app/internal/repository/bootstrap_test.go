@@ -0,0 +1,176 @@
+package mongodb_test
+
+import (
+	"apikey/internal/model/apikey"
+	mongodb "apikey/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// newTestDatabase connects to USER_VAR_TEST_MONGO_URI and returns an empty
+// database dropped when the test ends.
+func newTestDatabase(t *testing.T) (*mongo.Client, *mongo.Database) {
+	t.Helper()
+	uri := os.Getenv("USER_VAR_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("USER_VAR_TEST_MONGO_URI not set")
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(ctx) })
+
+	db := client.Database(fmt.Sprintf("apikey_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() { db.Drop(ctx) })
+	return client, db
+}
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// indexes returns the options of the indexes of collection by name.
+func indexes(t *testing.T, db *mongo.Database, collection string) map[string]bson.M {
+	t.Helper()
+	cursor, err := db.Collection(collection).Indexes().List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var specs []bson.M
+	if err := cursor.All(context.Background(), &specs); err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]bson.M{}
+	for _, spec := range specs {
+		byName[spec["name"].(string)] = spec
+	}
+	return byName
+}
+
+// expireAfter reads the expireAfterSeconds of an index, whose type depends
+// on the server version and how it was last set.
+func expireAfter(index bson.M) int64 {
+	switch v := index["expireAfterSeconds"].(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return -1
+}
+
+func TestBootstrap(t *testing.T) {
+	client, db := newTestDatabase(t)
+	ctx := context.Background()
+	opts := mongodb.BootstrapOptions{Database: db.Name(), UsageRetention: 24 * time.Hour}
+
+	// An index of an older build, without the partial filter.
+	_, err := db.Collection(mongodb.ApiKeyCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "apiKey", Value: 1}},
+		Options: options.Index().SetName("uk_apiKey").SetUnique(true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := mongodb.Bootstrap(ctx, newTestLogger(), client, opts); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+
+	apikeyIndexes := indexes(t, db, mongodb.ApiKeyCollection)
+	for _, name := range []string{"uk_apiKeyHash", "uk_apiKey", "uk_clientId"} {
+		index, ok := apikeyIndexes[name]
+		if !ok {
+			t.Errorf("index %s missing from %v", name, apikeyIndexes)
+			continue
+		}
+		if index["unique"] != true || index["partialFilterExpression"] == nil {
+			t.Errorf("index %s = %v, want unique and partial", name, index)
+		}
+	}
+	if ttl := indexes(t, db, mongodb.UsageCollection)["ttl_updatedAt"]; expireAfter(ttl) != 86400 {
+		t.Errorf("ttl_updatedAt = %v", ttl)
+	}
+
+	opts.UsageRetention = 48 * time.Hour
+	if err := mongodb.Bootstrap(ctx, newTestLogger(), client, opts); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := indexes(t, db, mongodb.UsageCollection)["ttl_updatedAt"]; expireAfter(ttl) != 172800 {
+		t.Errorf("ttl_updatedAt after a retention change = %v", ttl)
+	}
+
+	version, err := mongodb.ReadSchemaVersion(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != mongodb.SchemaVersion {
+		t.Errorf("schema version = %d, want %d", version, mongodb.SchemaVersion)
+	}
+
+	keys := db.Collection(mongodb.ApiKeyCollection)
+	for _, clientID := range []string{"client-1", "client-2"} {
+		doc := bson.M{"apiKeyHash": apikey.Hash(clientID), "clientId": clientID, "schemaVersion": mongodb.SchemaVersion}
+		if _, err := keys.InsertOne(ctx, doc); err != nil {
+			t.Fatalf("migrated keys without apiKey collide: %v", err)
+		}
+	}
+	_, err = keys.InsertOne(ctx, bson.M{"apiKeyHash": apikey.Hash("client-1"), "clientId": "client-3"})
+	if !mongo.IsDuplicateKeyError(err) {
+		t.Errorf("duplicate key hash = %v, want a duplicate key error", err)
+	}
+}
+
+func TestBootstrapWaitsForTheMigration(t *testing.T) {
+	client, db := newTestDatabase(t)
+	ctx := context.Background()
+	opts := mongodb.BootstrapOptions{Database: db.Name(), UsageRetention: time.Hour}
+
+	if _, err := db.Collection(mongodb.ApiKeyCollection).InsertOne(ctx, bson.M{"apiKey": "key-1", "clientid": "client-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mongodb.Bootstrap(ctx, newTestLogger(), client, opts); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := mongodb.ReadSchemaVersion(ctx, db); err != nil || version != 0 {
+		t.Errorf("schema version with a pending key = %d, %v, want 0", version, err)
+	}
+
+	repository := mongodb.NewApiKeyRepository(newTestLogger(), client, db.Name(), mongodb.ApiKeyCollection)
+	if key, err := repository.ValidateApiKey(ctx, "key-1"); err != nil || key.ClientID != "client-1" {
+		t.Errorf("pending key = %+v, %v, want it found by the key", key, err)
+	}
+}
+
+func TestBootstrapSchemaTooNew(t *testing.T) {
+	client, db := newTestDatabase(t)
+	ctx := context.Background()
+
+	_, err := db.Collection(mongodb.SchemaVersionCollection).InsertOne(ctx, bson.M{"_id": "apikey", "version": mongodb.SchemaVersion + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mongodb.Bootstrap(ctx, newTestLogger(), client, mongodb.BootstrapOptions{Database: db.Name()})
+	if !errors.Is(err, mongodb.ErrSchemaTooNew) {
+		t.Errorf("Bootstrap = %v, want %v", err, mongodb.ErrSchemaTooNew)
+	}
+}
@@ -0,0 +1,44 @@
+package mongodb
+
+import (
+	"apikey/internal/errormap"
+	"apikey/internal/model/company"
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CompanyRepository interface {
+	GetCompany(ctx context.Context, id int64) (*company.Company, error)
+}
+
+type companyRepository struct {
+	logger     *logrus.Logger
+	collection *mongo.Collection
+}
+
+func NewCompanyRepository(logger *logrus.Logger, db *mongo.Client, dbName, collectionName string) CompanyRepository {
+	collection := db.Database(dbName).Collection(collectionName)
+	return &companyRepository{
+		logger:     logger,
+		collection: collection,
+	}
+}
+
+func (u *companyRepository) GetCompany(ctx context.Context, id int64) (*company.Company, error) {
+	var dbCompany company.Company
+	filter := bson.M{"id": id}
+
+	err := u.collection.FindOne(ctx, filter).Decode(&dbCompany)
+	if err == mongo.ErrNoDocuments {
+		u.logger.WithContext(ctx).Infof("Company %d not found", id)
+		return nil, errormap.ErrCommerceNotFound
+	} else if err != nil {
+		u.logger.WithContext(ctx).Errorf("error retrieving company : %v", err)
+		return nil, err
+	}
+
+	return &dbCompany, nil
+}
@@ -0,0 +1,82 @@
+package mongodb_test
+
+import (
+	"apikey/internal/model/apikey"
+	"apikey/internal/model/company"
+	"apikey/internal/model/gracePeriod"
+	mongodb "apikey/internal/repository"
+	"apikey/internal/repository/repositorytest"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TestConformance runs the repository suite against the database at
+// USER_VAR_TEST_MONGO_URI, each test in its own database.
+func TestConformance(t *testing.T) {
+	uri := os.Getenv("USER_VAR_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("USER_VAR_TEST_MONGO_URI not set")
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(ctx) })
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Backend {
+		dbName := fmt.Sprintf("apikey_test_%d", time.Now().UnixNano())
+		db := client.Database(dbName)
+		t.Cleanup(func() { db.Drop(ctx) })
+
+		insert := func(t *testing.T, collection string, document interface{}, extra bson.M) {
+			t.Helper()
+			data, err := bson.Marshal(document)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc bson.M
+			if err := bson.Unmarshal(data, &doc); err != nil {
+				t.Fatal(err)
+			}
+			for key, value := range extra {
+				doc[key] = value
+			}
+			if _, err := db.Collection(collection).InsertOne(ctx, doc); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return repositorytest.Backend{
+			ApiKeys:      mongodb.NewApiKeyRepository(logger, client, dbName, mongodb.ApiKeyCollection),
+			Companies:    mongodb.NewCompanyRepository(logger, client, dbName, mongodb.CompanyCollection),
+			Usage:        mongodb.NewUsageRepository(logger, client, dbName, mongodb.UsageCollection),
+			GracePeriods: mongodb.NewGracePeriodRepository(logger, client, dbName, mongodb.CommerceRuleCollection),
+			Health:       mongodb.NewHealthRepository(logger, client),
+
+			PutApiKey: func(t *testing.T, apiKey string, key apikey.ApiKey) {
+				key.SchemaVersion = mongodb.SchemaVersion
+				insert(t, mongodb.ApiKeyCollection, key, bson.M{"apiKeyHash": apikey.Hash(apiKey)})
+			},
+			PutCompany: func(t *testing.T, c company.Company) {
+				insert(t, mongodb.CompanyCollection, c, nil)
+			},
+			PutGracePeriod: func(t *testing.T, rule gracePeriod.CommerceRule) {
+				insert(t, mongodb.CommerceRuleCollection, rule, nil)
+			},
+		}
+	})
+}
@@ -1,24 +1,31 @@
 package mongodb
 
 import (
+	"apikey/pkg/env"
 	"context"
-	"os"
 	"time"
 
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func Connection() (*mongo.Client, error) {
-	var (
-		dbUri = os.Getenv("USER_VAR_DB_MONGO_URI")
-	)
-
-	clientOptions := options.Client().ApplyURI(dbUri)
-
+// Connection connects to USER_VAR_DB_MONGO_URI, the monitors see every
+// command the client sends.
+func Connection(monitors ...*event.CommandMonitor) (*mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	dbUri, err := env.Get(ctx, "USER_VAR_DB_MONGO_URI")
+	if err != nil {
+		return nil, err
+	}
+
+	clientOptions := options.Client().ApplyURI(dbUri)
+	if len(monitors) > 0 {
+		clientOptions.SetMonitor(combineMonitors(monitors))
+	}
+
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		return nil, err
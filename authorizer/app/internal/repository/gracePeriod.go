@@ -1,11 +1,44 @@
 package mongodb
 
 import (
+	"apikey/internal/errormap"
 	"apikey/internal/model/gracePeriod"
 	"context"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type GracePeriodRepository interface {
-	ValidationGracePeriod(ctx context.Context) (*gracePeriod.CommerceRule, error)
+	ValidationGracePeriod(ctx context.Context, idCommerce string) (*gracePeriod.CommerceRule, error)
+}
+
+type gracePeriodRepository struct {
+	logger     *logrus.Logger
+	collection *mongo.Collection
+}
+
+func NewGracePeriodRepository(logger *logrus.Logger, db *mongo.Client, dbName, collectionName string) GracePeriodRepository {
+	collection := db.Database(dbName).Collection(collectionName)
+	return &gracePeriodRepository{
+		logger:     logger,
+		collection: collection,
+	}
 }
 
+func (u *gracePeriodRepository) ValidationGracePeriod(ctx context.Context, idCommerce string) (*gracePeriod.CommerceRule, error) {
+	var rule gracePeriod.CommerceRule
+	filter := bson.M{"id_commerce": idCommerce}
+
+	err := u.collection.FindOne(ctx, filter).Decode(&rule)
+	if err == mongo.ErrNoDocuments {
+		u.logger.WithContext(ctx).Infof("Commerce rule %s not found", idCommerce)
+		return nil, errormap.ErrNoRows
+	} else if err != nil {
+		u.logger.WithContext(ctx).Errorf("error retrieving commerce rule : %v", err)
+		return nil, err
+	}
+
+	return &rule, nil
+}
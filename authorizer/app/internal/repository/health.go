@@ -0,0 +1,33 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+}
+
+type healthRepository struct {
+	logger *logrus.Logger
+	client *mongo.Client
+}
+
+func NewHealthRepository(logger *logrus.Logger, client *mongo.Client) HealthRepository {
+	return &healthRepository{
+		logger: logger,
+		client: client,
+	}
+}
+
+func (r *healthRepository) Ping(ctx context.Context) error {
+	if err := r.client.Ping(ctx, readpref.Primary()); err != nil {
+		r.logger.WithContext(ctx).Errorf("Error pinging database: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"apikey/internal/errormap"
+	"apikey/internal/model/apikey"
+	"context"
+	"sync"
+)
+
+type ApiKeyRepository struct {
+	failure
+	mu   sync.RWMutex
+	keys map[string]apikey.ApiKey
+}
+
+func NewApiKeyRepository() *ApiKeyRepository {
+	return &ApiKeyRepository{keys: map[string]apikey.ApiKey{}}
+}
+
+// Put stores key under apiKey, replacing any previous one.
+func (r *ApiKeyRepository) Put(apiKey string, key apikey.ApiKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[apiKey] = key
+}
+
+func (r *ApiKeyRepository) ValidateApiKey(ctx context.Context, apiKey string) (*apikey.ApiKey, error) {
+	if err := r.failed(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	key, ok := r.keys[apiKey]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, errormap.ErrNoRows
+	}
+	return &key, nil
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"apikey/internal/errormap"
+	"apikey/internal/model/company"
+	"context"
+	"sync"
+)
+
+type CompanyRepository struct {
+	failure
+	mu        sync.RWMutex
+	companies map[int64]company.Company
+}
+
+func NewCompanyRepository() *CompanyRepository {
+	return &CompanyRepository{companies: map[int64]company.Company{}}
+}
+
+// Put stores c under its id, replacing any previous one.
+func (r *CompanyRepository) Put(c company.Company) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.companies[c.ID] = c
+}
+
+func (r *CompanyRepository) GetCompany(ctx context.Context, id int64) (*company.Company, error) {
+	if err := r.failed(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	c, ok := r.companies[id]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, errormap.ErrCommerceNotFound
+	}
+	return &c, nil
+}
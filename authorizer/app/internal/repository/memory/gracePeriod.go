@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"apikey/internal/errormap"
+	"apikey/internal/model/gracePeriod"
+	"context"
+	"sync"
+)
+
+type GracePeriodRepository struct {
+	failure
+	mu    sync.RWMutex
+	rules map[string]gracePeriod.CommerceRule
+}
+
+func NewGracePeriodRepository() *GracePeriodRepository {
+	return &GracePeriodRepository{rules: map[string]gracePeriod.CommerceRule{}}
+}
+
+// Put stores rule under its commerce id, replacing any previous one.
+func (r *GracePeriodRepository) Put(rule gracePeriod.CommerceRule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules[rule.IdCommerce] = rule
+}
+
+func (r *GracePeriodRepository) ValidationGracePeriod(ctx context.Context, idCommerce string) (*gracePeriod.CommerceRule, error) {
+	if err := r.failed(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	rule, ok := r.rules[idCommerce]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, errormap.ErrNoRows
+	}
+	return &rule, nil
+}
@@ -0,0 +1,15 @@
+package memory
+
+import "context"
+
+type HealthRepository struct {
+	failure
+}
+
+func NewHealthRepository() *HealthRepository {
+	return &HealthRepository{}
+}
+
+func (r *HealthRepository) Ping(ctx context.Context) error {
+	return r.failed()
+}
@@ -0,0 +1,25 @@
+// Package memory implements the repository interfaces in memory, so the
+// service and handler logic can be tested without a database.
+package memory
+
+import "sync"
+
+// failure makes a repository return err from every call, to simulate the
+// database being down.
+type failure struct {
+	mu  sync.RWMutex
+	err error
+}
+
+// Fail makes every call fail with err until Fail(nil).
+func (f *failure) Fail(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func (f *failure) failed() error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.err
+}
@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"apikey/internal/model/apikey"
+	"apikey/internal/model/company"
+	"apikey/internal/model/gracePeriod"
+	"apikey/internal/repository/repositorytest"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Backend {
+		var (
+			apiKeys      = NewApiKeyRepository()
+			companies    = NewCompanyRepository()
+			gracePeriods = NewGracePeriodRepository()
+		)
+		return repositorytest.Backend{
+			ApiKeys:      apiKeys,
+			Companies:    companies,
+			Usage:        NewUsageRepository(),
+			GracePeriods: gracePeriods,
+			Health:       NewHealthRepository(),
+
+			PutApiKey: func(t *testing.T, apiKey string, key apikey.ApiKey) {
+				apiKeys.Put(apiKey, key)
+			},
+			PutCompany: func(t *testing.T, c company.Company) {
+				companies.Put(c)
+			},
+			PutGracePeriod: func(t *testing.T, rule gracePeriod.CommerceRule) {
+				gracePeriods.Put(rule)
+			},
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"apikey/internal/model/usage"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type usageKey struct {
+	clientID string
+	day      string
+	route    string
+}
+
+type UsageRepository struct {
+	failure
+	mu     sync.Mutex
+	usages map[usageKey]usage.Usage
+}
+
+func NewUsageRepository() *UsageRepository {
+	return &UsageRepository{usages: map[usageKey]usage.Usage{}}
+}
+
+func (r *UsageRepository) Record(ctx context.Context, clientID, day, route string, at time.Time) error {
+	if err := r.failed(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := usageKey{clientID: clientID, day: day, route: route}
+	u := r.usages[key]
+	u.ClientID, u.Day, u.Route = clientID, day, route
+	u.Count++
+	u.UpdatedAt = at
+	r.usages[key] = u
+	return nil
+}
+
+func (r *UsageRepository) Series(ctx context.Context, clientID, from, to string) (usage.Series, error) {
+	if err := r.failed(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	points := map[string]*usage.Point{}
+	for key, u := range r.usages {
+		if key.clientID != clientID || key.day < from || key.day > to {
+			continue
+		}
+		point, ok := points[key.day]
+		if !ok {
+			point = &usage.Point{Day: key.day, Routes: map[string]int64{}}
+			points[key.day] = point
+		}
+		point.Count += u.Count
+		point.Routes[key.route] += u.Count
+	}
+	r.mu.Unlock()
+
+	series := usage.Series{}
+	for _, point := range points {
+		series = append(series, *point)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Day < series[j].Day })
+	return series, nil
+}
+
+func (r *UsageRepository) TopConsumers(ctx context.Context, from, to string, limit int) (usage.Consumers, error) {
+	if err := r.failed(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	counts := map[string]int64{}
+	for key, u := range r.usages {
+		if key.day >= from && key.day <= to {
+			counts[key.clientID] += u.Count
+		}
+	}
+	r.mu.Unlock()
+
+	consumers := usage.Consumers{}
+	for clientID, count := range counts {
+		consumers = append(consumers, usage.Consumer{ClientID: clientID, Count: count})
+	}
+	sort.Slice(consumers, func(i, j int) bool {
+		if consumers[i].Count != consumers[j].Count {
+			return consumers[i].Count > consumers[j].Count
+		}
+		return consumers[i].ClientID < consumers[j].ClientID
+	})
+	if len(consumers) > limit {
+		consumers = consumers[:limit]
+	}
+	return consumers, nil
+}
@@ -0,0 +1,144 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const defaultMigrateBatchSize = 500
+
+type MigrateOptions struct {
+	// Database is the database to migrate, Database by default.
+	Database string
+	// BatchSize is how many documents are rewritten per round trip.
+	BatchSize int
+	// DryRun only counts the documents to migrate.
+	DryRun bool
+}
+
+type MigrateProgress struct {
+	Total    int64
+	Migrated int64
+	Failed   int64
+}
+
+// outdatedApiKeys selects the api keys below SchemaVersion.
+func outdatedApiKeys() bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"schemaVersion": bson.M{"$exists": false}},
+		bson.M{"schemaVersion": bson.M{"$lt": SchemaVersion}},
+	}}
+}
+
+// MigrateApiKeys rewrites the api keys below SchemaVersion in batches ordered
+// by _id, logging the progress after each batch. Migrated documents leave the
+// selection, so a run that was interrupted resumes where it stopped, and
+// documents that fail are skipped and retried by the next run. The schema
+// version is recorded once every document is migrated.
+func MigrateApiKeys(ctx context.Context, logger *logrus.Logger, client *mongo.Client, opts MigrateOptions) (MigrateProgress, error) {
+	var progress MigrateProgress
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultMigrateBatchSize
+	}
+	if opts.Database == "" {
+		opts.Database = Database
+	}
+	db := client.Database(opts.Database)
+
+	version, err := ReadSchemaVersion(ctx, db)
+	if err != nil {
+		return progress, err
+	}
+	if version > SchemaVersion {
+		return progress, fmt.Errorf("%w: database at version %d, build at %d", ErrSchemaTooNew, version, SchemaVersion)
+	}
+
+	collection := db.Collection(ApiKeyCollection)
+	progress.Total, err = collection.CountDocuments(ctx, outdatedApiKeys())
+	if err != nil {
+		return progress, fmt.Errorf("error counting outdated api keys : %w", err)
+	}
+	logger.WithContext(ctx).Infof("%d api keys to migrate to schema version %d", progress.Total, SchemaVersion)
+	if opts.DryRun {
+		return progress, nil
+	}
+
+	var lastID interface{}
+	for {
+		filter := outdatedApiKeys()
+		if lastID != nil {
+			filter = bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$gt": lastID}}}}
+		}
+
+		docs, err := findBatch(ctx, collection, filter, opts.BatchSize)
+		if err != nil {
+			return progress, err
+		}
+		if len(docs) == 0 {
+			break
+		}
+		lastID = docs[len(docs)-1]["_id"]
+
+		migrated, failed, err := rewriteBatch(ctx, logger, collection, docs)
+		if err != nil {
+			return progress, err
+		}
+		progress.Migrated += migrated
+		progress.Failed += failed
+		logger.WithContext(ctx).Infof("Migrated %d/%d api keys, %d failed", progress.Migrated, progress.Total, progress.Failed)
+	}
+
+	if progress.Failed > 0 {
+		return progress, fmt.Errorf("%d api keys could not be migrated", progress.Failed)
+	}
+	if version < SchemaVersion {
+		if err := writeSchemaVersion(ctx, db, SchemaVersion); err != nil {
+			return progress, err
+		}
+		logger.WithContext(ctx).Infof("Schema version %d recorded", SchemaVersion)
+	}
+	return progress, nil
+}
+
+func findBatch(ctx context.Context, collection *mongo.Collection, filter bson.M, size int) ([]bson.M, error) {
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(size)))
+	if err != nil {
+		return nil, fmt.Errorf("error reading api keys : %w", err)
+	}
+
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("error reading api keys : %w", err)
+	}
+	return docs, nil
+}
+
+// rewriteBatch upgrades and replaces the documents, unless they were
+// migrated since they were read.
+func rewriteBatch(ctx context.Context, logger *logrus.Logger, collection *mongo.Collection, docs []bson.M) (int64, int64, error) {
+	models := make([]mongo.WriteModel, 0, len(docs))
+	for _, doc := range docs {
+		filter := bson.M{"$and": bson.A{outdatedApiKeys(), bson.M{"_id": doc["_id"]}}}
+		UpgradeApiKey(doc)
+		models = append(models, mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(doc))
+	}
+
+	result, err := collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			logger.WithContext(ctx).Errorf("Error migrating api key %v: %v", docs[writeErr.Index]["_id"], writeErr.Message)
+		}
+		return result.ModifiedCount, int64(len(bulkErr.WriteErrors)), nil
+	} else if err != nil {
+		return 0, 0, fmt.Errorf("error writing api keys : %w", err)
+	}
+	return result.ModifiedCount, 0, nil
+}
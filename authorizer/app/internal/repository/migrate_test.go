@@ -0,0 +1,179 @@
+package mongodb_test
+
+import (
+	"apikey/internal/model/apikey"
+	mongodb "apikey/internal/repository"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// cancelHook cancels the migration once it logged a batch, as if the
+// command had been interrupted.
+type cancelHook struct {
+	cancel context.CancelFunc
+}
+
+func (h cancelHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h cancelHook) Fire(entry *logrus.Entry) error {
+	if strings.HasPrefix(entry.Message, "Migrated ") {
+		h.cancel()
+	}
+	return nil
+}
+
+// insertLegacyApiKeys inserts n api keys as the driver stored them before
+// schemaVersion, key-0 to key-n.
+func insertLegacyApiKeys(t *testing.T, db *mongo.Database, n int) {
+	t.Helper()
+	docs := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		docs = append(docs, bson.M{
+			"apiKey":    fmt.Sprintf("key-%d", i),
+			"clientid":  fmt.Sprintf("client-%d", i),
+			"isactive":  true,
+			"expiredat": time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+	}
+	if _, err := db.Collection(mongodb.ApiKeyCollection).InsertMany(context.Background(), docs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func countOutdated(t *testing.T, db *mongo.Database) int64 {
+	t.Helper()
+	n, err := db.Collection(mongodb.ApiKeyCollection).CountDocuments(context.Background(),
+		bson.M{"schemaVersion": bson.M{"$ne": mongodb.SchemaVersion}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestMigrateApiKeys(t *testing.T) {
+	client, db := newTestDatabase(t)
+	ctx := context.Background()
+	insertLegacyApiKeys(t, db, 7)
+
+	if err := mongodb.Bootstrap(ctx, newTestLogger(), client, mongodb.BootstrapOptions{Database: db.Name(), UsageRetention: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := mongodb.ReadSchemaVersion(ctx, db); version != 0 {
+		t.Fatalf("Bootstrap recorded version %d with keys to migrate", version)
+	}
+
+	progress, err := mongodb.MigrateApiKeys(ctx, newTestLogger(), client, mongodb.MigrateOptions{Database: db.Name(), DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Total != 7 || progress.Migrated != 0 || countOutdated(t, db) != 7 {
+		t.Fatalf("dry run = %+v, %d outdated", progress, countOutdated(t, db))
+	}
+
+	// The first run stops after its first batch.
+	interrupted, cancel := context.WithCancel(ctx)
+	logger := newTestLogger()
+	logger.AddHook(cancelHook{cancel: cancel})
+	defer cancel()
+	progress, err = mongodb.MigrateApiKeys(interrupted, logger, client, mongodb.MigrateOptions{Database: db.Name(), BatchSize: 3})
+	if err == nil {
+		t.Fatal("interrupted run returned no error")
+	}
+	if progress.Total != 7 || progress.Migrated != 3 || countOutdated(t, db) != 4 {
+		t.Fatalf("interrupted run = %+v, %d outdated", progress, countOutdated(t, db))
+	}
+	if version, _ := mongodb.ReadSchemaVersion(ctx, db); version != 0 {
+		t.Fatalf("interrupted run recorded version %d", version)
+	}
+
+	logger, hook := test.NewNullLogger()
+	progress, err = mongodb.MigrateApiKeys(ctx, logger, client, mongodb.MigrateOptions{Database: db.Name(), BatchSize: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Total != 4 || progress.Migrated != 4 || progress.Failed != 0 {
+		t.Errorf("resumed run = %+v, want the 4 keys left", progress)
+	}
+	var batches []string
+	for _, entry := range hook.AllEntries() {
+		if strings.HasPrefix(entry.Message, "Migrated ") {
+			batches = append(batches, entry.Message)
+		}
+	}
+	want := []string{"Migrated 3/4 api keys, 0 failed", "Migrated 4/4 api keys, 0 failed"}
+	if strings.Join(batches, "|") != strings.Join(want, "|") {
+		t.Errorf("progress = %q, want %q", batches, want)
+	}
+
+	if countOutdated(t, db) != 0 {
+		t.Errorf("%d keys left to migrate", countOutdated(t, db))
+	}
+	if version, _ := mongodb.ReadSchemaVersion(ctx, db); version != mongodb.SchemaVersion {
+		t.Errorf("schema version = %d, want %d", version, mongodb.SchemaVersion)
+	}
+
+	repository := mongodb.NewApiKeyRepository(newTestLogger(), client, db.Name(), mongodb.ApiKeyCollection)
+	key, err := repository.ValidateApiKey(ctx, "key-5")
+	if err != nil || key.ClientID != "client-5" || !key.IsActive {
+		t.Errorf("migrated key = %+v, %v", key, err)
+	}
+	var doc bson.M
+	if err := db.Collection(mongodb.ApiKeyCollection).FindOne(ctx, bson.M{"clientId": "client-5"}).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc["apiKey"]; ok || doc["apiKeyHash"] != apikey.Hash("key-5") {
+		t.Errorf("migrated document = %v, want the key replaced by its hash", doc)
+	}
+
+	progress, err = mongodb.MigrateApiKeys(ctx, newTestLogger(), client, mongodb.MigrateOptions{Database: db.Name()})
+	if err != nil || progress.Total != 0 {
+		t.Errorf("run on a migrated database = %+v, %v", progress, err)
+	}
+}
+
+func TestMigrateApiKeysFailures(t *testing.T) {
+	client, db := newTestDatabase(t)
+	ctx := context.Background()
+	if err := mongodb.Bootstrap(ctx, newTestLogger(), client, mongodb.BootstrapOptions{Database: db.Name(), UsageRetention: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	insertLegacyApiKeys(t, db, 2)
+
+	// A migrated copy of key-1 makes its hash collide.
+	_, err := db.Collection(mongodb.ApiKeyCollection).InsertOne(ctx, bson.M{
+		"apiKeyHash":    apikey.Hash("key-1"),
+		"clientId":      "client-copy",
+		"schemaVersion": mongodb.SchemaVersion,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	progress, err := mongodb.MigrateApiKeys(ctx, newTestLogger(), client, mongodb.MigrateOptions{Database: db.Name()})
+	if err == nil {
+		t.Fatal("expected an error for the key that failed")
+	}
+	if progress.Total != 2 || progress.Migrated != 1 || progress.Failed != 1 {
+		t.Errorf("progress = %+v, want 1 migrated and 1 failed", progress)
+	}
+	if version, _ := mongodb.ReadSchemaVersion(ctx, db); version == mongodb.SchemaVersion {
+		t.Error("schema version recorded with a key left to migrate")
+	}
+
+	if err := mongodb.Bootstrap(ctx, newTestLogger(), client, mongodb.BootstrapOptions{Database: db.Name(), UsageRetention: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := mongodb.ReadSchemaVersion(ctx, db); version == mongodb.SchemaVersion {
+		t.Error("Bootstrap recorded the schema version with a key left to migrate")
+	}
+}
@@ -0,0 +1,52 @@
+package mongodb
+
+import (
+	"apikey/pkg/metrics"
+	"context"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// MetricsMonitor records the latency of every command sent to the
+// database, failed ones included, by command name.
+func MetricsMonitor(m metrics.Metrics) *event.CommandMonitor {
+	record := func(name string, finished event.CommandFinishedEvent) {
+		m.Put(metrics.DBLatency, float64(finished.Duration.Microseconds())/1000, metrics.Milliseconds,
+			metrics.Dimensions{metrics.Operation: name})
+	}
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			record(e.CommandName, e.CommandFinishedEvent)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			record(e.CommandName, e.CommandFinishedEvent)
+		},
+	}
+}
+
+// combineMonitors lets several monitors watch the same client.
+func combineMonitors(monitors []*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Started != nil {
+					monitor.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, monitor := range monitors {
+				if monitor.Succeeded != nil {
+					monitor.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Failed != nil {
+					monitor.Failed(ctx, e)
+				}
+			}
+		},
+	}
+}
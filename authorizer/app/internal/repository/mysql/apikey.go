@@ -0,0 +1,98 @@
+package mysql
+
+import (
+	"apikey/internal/errormap"
+	"apikey/internal/model/apikey"
+	mongodb "apikey/internal/repository"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+type apikeyRepository struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewApiKeyRepository(logger *logrus.Logger, db *sql.DB) mongodb.ApiKeyRepository {
+	return &apikeyRepository{
+		logger: logger,
+		db:     db,
+	}
+}
+
+const selectApiKey = `SELECT client_id, is_active, expired_at, request_count, type, environment,
+	company_id, timezone, plan_name, daily_limit, monthly_limit, total_limit, rate_per_second, burst,
+	platform_data, allowed_origins, schedules, stages
+	FROM api_key WHERE api_key_hash = ?`
+
+// ValidateApiKey looks the key up by its hash, the table does not store
+// api keys.
+func (u *apikeyRepository) ValidateApiKey(ctx context.Context, apiKey string) (*apikey.ApiKey, error) {
+	var (
+		dbApiKey  apikey.ApiKey
+		expiredAt sql.NullTime
+		platform  nullJSON
+		origins   nullJSON
+		schedules nullJSON
+		stages    nullJSON
+	)
+
+	err := u.db.QueryRowContext(ctx, selectApiKey, apikey.Hash(apiKey)).Scan(
+		&dbApiKey.ClientID, &dbApiKey.IsActive, &expiredAt, &dbApiKey.RequestCount,
+		&dbApiKey.Type, &dbApiKey.Environment, &dbApiKey.CompanyID, &dbApiKey.Timezone,
+		&dbApiKey.UsageLimits.Plan, &dbApiKey.UsageLimits.DailyLimit, &dbApiKey.UsageLimits.MonthlyLimit,
+		&dbApiKey.UsageLimits.Limit, &dbApiKey.UsageLimits.RatePerSecond, &dbApiKey.UsageLimits.Burst,
+		&platform, &origins, &schedules, &stages,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		u.logger.WithContext(ctx).Infof("ApiKey %s not found", apikey.Fingerprint(apiKey))
+		return nil, errormap.ErrNoRows
+	} else if err != nil {
+		u.logger.WithContext(ctx).Errorf("error retrieving ApiKey : %v", err)
+		return nil, err
+	}
+	dbApiKey.ExpiredAt = expiredAt.Time
+
+	columns := map[string]struct {
+		data  nullJSON
+		value interface{}
+	}{
+		"platform_data":   {platform, &dbApiKey.PlatformData},
+		"allowed_origins": {origins, &dbApiKey.AllowedOrigins},
+		"schedules":       {schedules, &dbApiKey.Schedules},
+		"stages":          {stages, &dbApiKey.Stages},
+	}
+	for column, c := range columns {
+		if len(c.data) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(c.data, c.value); err != nil {
+			u.logger.WithContext(ctx).Errorf("error decoding %s of ApiKey %s : %v", column, dbApiKey.ClientID, err)
+			return nil, fmt.Errorf("invalid %s : %w", column, err)
+		}
+	}
+
+	return &dbApiKey, nil
+}
+
+// nullJSON scans nullable JSON columns.
+type nullJSON []byte
+
+func (j *nullJSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case string:
+		*j = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JSON", value)
+	}
+	return nil
+}
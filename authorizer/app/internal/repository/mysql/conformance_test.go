@@ -0,0 +1,189 @@
+package mysql
+
+import (
+	"apikey/internal/model/apikey"
+	"apikey/internal/repository/repositorytest"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+// newDatabase creates an empty database on the server at
+// USER_VAR_TEST_MYSQL_DSN, dropped when the test ends.
+func newDatabase(t *testing.T) *sql.DB {
+	dsn := os.Getenv("USER_VAR_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("USER_VAR_TEST_MYSQL_DSN not set")
+	}
+	ctx := context.Background()
+
+	admin, err := Connection(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.DBName = fmt.Sprintf("apikey_test_%d", time.Now().UnixNano())
+	if _, err := admin.ExecContext(ctx, "CREATE DATABASE "+cfg.DBName); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.ExecContext(ctx, "DROP DATABASE "+cfg.DBName) })
+
+	db, err := Connection(ctx, cfg.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// TestConformance runs the repository suite against MySQL, each test in
+// its own migrated database.
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Backend {
+		logger := newTestLogger()
+		db := newDatabase(t)
+		if err := Migrate(context.Background(), logger, db); err != nil {
+			t.Fatal(err)
+		}
+
+		return repositorytest.Backend{
+			ApiKeys: NewApiKeyRepository(logger, db),
+			Usage:   NewUsageRepository(logger, db),
+			Health:  NewHealthRepository(logger, db),
+
+			PutApiKey: func(t *testing.T, apiKey string, key apikey.ApiKey) {
+				insertApiKey(t, db, apiKey, key)
+			},
+		}
+	})
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	db := newDatabase(t)
+	for i := 0; i < 2; i++ {
+		if err := Migrate(context.Background(), newTestLogger(), db); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+
+	var applied int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := migrations.ReadDir("migrations")
+	if applied != len(files) {
+		t.Errorf("%d migrations recorded, want %d", applied, len(files))
+	}
+}
+
+func TestMigrateConcurrently(t *testing.T) {
+	db := newDatabase(t)
+
+	errs := make(chan error, 3)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			errs <- Migrate(context.Background(), newTestLogger(), db)
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("concurrent run: %v", err)
+		}
+	}
+
+	var applied int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := migrations.ReadDir("migrations")
+	if applied != len(files) {
+		t.Errorf("%d migrations recorded, want %d", applied, len(files))
+	}
+}
+
+// TestMigrateHashesApiKeys applies the migrations before 0003 to a table
+// holding a plain api key, which the rest of them replace by its hash.
+func TestMigrateHashesApiKeys(t *testing.T) {
+	db := newDatabase(t)
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, `CREATE TABLE schema_migrations (
+		version    VARCHAR(255) NOT NULL PRIMARY KEY,
+		applied_at DATETIME(3)  NOT NULL
+	)`); err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []string{"0001_create_api_key", "0002_create_api_key_usage"} {
+		script, err := migrations.ReadFile("migrations/" + version + ".sql")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.ExecContext(ctx, string(script)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.ExecContext(ctx, "INSERT INTO schema_migrations (version, applied_at) VALUES (?, UTC_TIMESTAMP(3))", version); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO api_key (api_key, client_id, is_active) VALUES ('key-1', 'client-1', TRUE)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Migrate(ctx, newTestLogger(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := NewApiKeyRepository(newTestLogger(), db).ValidateApiKey(ctx, "key-1")
+	if err != nil || key.ClientID != "client-1" {
+		t.Fatalf("ValidateApiKey after the migration = %+v, %v", key, err)
+	}
+	var plain int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'api_key' AND column_name = 'api_key'").Scan(&plain); err != nil {
+		t.Fatal(err)
+	}
+	if plain != 0 {
+		t.Error("api_key still stores the plain keys")
+	}
+}
+
+func insertApiKey(t *testing.T, db *sql.DB, apiKey string, key apikey.ApiKey) {
+	t.Helper()
+
+	encode := func(value interface{}) []byte {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	_, err := db.Exec(`INSERT INTO api_key (api_key_hash, client_id, is_active, expired_at, request_count, type,
+		environment, company_id, timezone, plan_name, daily_limit, monthly_limit, total_limit, rate_per_second, burst,
+		platform_data, allowed_origins, schedules, stages)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		apikey.Hash(apiKey), key.ClientID, key.IsActive, sql.NullTime{Time: key.ExpiredAt, Valid: !key.ExpiredAt.IsZero()}, key.RequestCount, key.Type,
+		key.Environment, key.CompanyID, key.Timezone, key.UsageLimits.Plan, key.UsageLimits.DailyLimit,
+		key.UsageLimits.MonthlyLimit, key.UsageLimits.Limit, key.UsageLimits.RatePerSecond, key.UsageLimits.Burst,
+		encode(key.PlatformData), encode(key.AllowedOrigins), encode(key.Schedules), encode(key.Stages))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,29 @@
+package mysql
+
+import (
+	mongodb "apikey/internal/repository"
+	"context"
+	"database/sql"
+
+	"github.com/sirupsen/logrus"
+)
+
+type healthRepository struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewHealthRepository(logger *logrus.Logger, db *sql.DB) mongodb.HealthRepository {
+	return &healthRepository{
+		logger: logger,
+		db:     db,
+	}
+}
+
+func (r *healthRepository) Ping(ctx context.Context) error {
+	if err := r.db.PingContext(ctx); err != nil {
+		r.logger.WithContext(ctx).Errorf("Error pinging database: %v", err)
+		return err
+	}
+	return nil
+}
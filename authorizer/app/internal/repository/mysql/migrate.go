@@ -0,0 +1,107 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrateLock prefixes the named lock held while migrating a database, so
+// runs started at the same time apply each migration once.
+const (
+	migrateLock        = "apikey_migrate:"
+	migrateLockTimeout = 60
+)
+
+// Migrate applies the embedded migrations not yet recorded in
+// schema_migrations, in file name order. It holds the migrateLock of the
+// database on a dedicated connection, a concurrent run waits for it and
+// then finds the migrations applied.
+func Migrate(ctx context.Context, logger *logrus.Logger, sqlDB *sql.DB) error {
+	db, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error opening migration connection : %w", err)
+	}
+	defer db.Close()
+
+	var locked sql.NullInt64
+	if err := db.QueryRowContext(ctx, "SELECT GET_LOCK(CONCAT(?, DATABASE()), ?)", migrateLock, migrateLockTimeout).Scan(&locked); err != nil {
+		return fmt.Errorf("error taking migration lock : %w", err)
+	}
+	if locked.Int64 != 1 {
+		return errors.New("migration lock held by another run")
+	}
+	defer db.ExecContext(context.Background(), "SELECT RELEASE_LOCK(CONCAT(?, DATABASE()))", migrateLock)
+
+	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    VARCHAR(255) NOT NULL PRIMARY KEY,
+		applied_at DATETIME(3)  NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations : %w", err)
+	}
+
+	applied := map[string]bool{}
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return fmt.Errorf("error reading schema_migrations : %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return err
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	files, err := migrations.ReadDir("migrations")
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		version := strings.TrimSuffix(name, ".sql")
+		if applied[version] {
+			continue
+		}
+
+		script, err := migrations.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return err
+		}
+		for _, statement := range strings.Split(string(script), ";") {
+			if strings.TrimSpace(statement) == "" {
+				continue
+			}
+			if _, err := db.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("error applying migration %s : %w", version, err)
+			}
+		}
+
+		if _, err := db.ExecContext(ctx, "INSERT INTO schema_migrations (version, applied_at) VALUES (?, UTC_TIMESTAMP(3))", version); err != nil {
+			return fmt.Errorf("error recording migration %s : %w", version, err)
+		}
+		logger.WithContext(ctx).Infof("Applied migration %s", version)
+	}
+
+	return nil
+}
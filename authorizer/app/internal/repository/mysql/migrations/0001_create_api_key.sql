@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS api_key (
+    id              BIGINT AUTO_INCREMENT PRIMARY KEY,
+    api_key         VARCHAR(255) NOT NULL,
+    client_id       VARCHAR(128) NOT NULL,
+    is_active       BOOLEAN      NOT NULL DEFAULT FALSE,
+    expired_at      DATETIME(3)  NULL,
+    request_count   INT          NOT NULL DEFAULT 0,
+    type            VARCHAR(32)  NOT NULL DEFAULT '',
+    environment     VARCHAR(32)  NOT NULL DEFAULT '',
+    company_id      BIGINT       NOT NULL DEFAULT 0,
+    timezone        VARCHAR(64)  NOT NULL DEFAULT '',
+    plan_name       VARCHAR(64)  NOT NULL DEFAULT '',
+    daily_limit     INT          NOT NULL DEFAULT 0,
+    monthly_limit   INT          NOT NULL DEFAULT 0,
+    total_limit     INT          NOT NULL DEFAULT 0,
+    rate_per_second DOUBLE       NOT NULL DEFAULT 0,
+    burst           INT          NOT NULL DEFAULT 0,
+    platform_data   JSON         NULL,
+    allowed_origins JSON         NULL,
+    schedules       JSON         NULL,
+    stages          JSON         NULL,
+    UNIQUE KEY uk_api_key (api_key),
+    UNIQUE KEY uk_client_id (client_id)
+);
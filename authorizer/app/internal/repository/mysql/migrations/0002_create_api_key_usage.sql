@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    client_id  VARCHAR(128) NOT NULL,
+    day        CHAR(10)     NOT NULL,
+    route      VARCHAR(255) NOT NULL,
+    count      BIGINT       NOT NULL DEFAULT 0,
+    updated_at DATETIME(3)  NOT NULL,
+    PRIMARY KEY (client_id, day, route),
+    KEY idx_day (day)
+);
@@ -0,0 +1,9 @@
+ALTER TABLE api_key ADD COLUMN api_key_hash CHAR(64) NULL AFTER id;
+
+UPDATE api_key SET api_key_hash = SHA2(api_key, 256);
+
+ALTER TABLE api_key
+    MODIFY api_key_hash CHAR(64) NOT NULL,
+    ADD UNIQUE KEY uk_api_key_hash (api_key_hash),
+    DROP KEY uk_api_key,
+    DROP COLUMN api_key;
@@ -0,0 +1,95 @@
+package mysql
+
+import (
+	"apikey/internal/model/usage"
+	mongodb "apikey/internal/repository"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type usageRepository struct {
+	logger *logrus.Logger
+	db     *sql.DB
+}
+
+func NewUsageRepository(logger *logrus.Logger, db *sql.DB) mongodb.UsageRepository {
+	return &usageRepository{
+		logger: logger,
+		db:     db,
+	}
+}
+
+func (u *usageRepository) Record(ctx context.Context, clientID, day, route string, at time.Time) error {
+	_, err := u.db.ExecContext(ctx, `INSERT INTO api_key_usage (client_id, day, route, count, updated_at)
+		VALUES (?, ?, ?, 1, ?)
+		ON DUPLICATE KEY UPDATE count = count + 1, updated_at = VALUES(updated_at)`,
+		clientID, day, route, at.UTC())
+	if err != nil {
+		u.logger.WithContext(ctx).Errorf("error recording usage of %s : %v", clientID, err)
+		return err
+	}
+
+	return nil
+}
+
+// Series returns one point per day with calls, days are YYYY-MM-DD so they
+// compare as strings.
+func (u *usageRepository) Series(ctx context.Context, clientID, from, to string) (usage.Series, error) {
+	rows, err := u.db.QueryContext(ctx, `SELECT day, route, count FROM api_key_usage
+		WHERE client_id = ? AND day BETWEEN ? AND ? ORDER BY day, route`, clientID, from, to)
+	if err != nil {
+		u.logger.WithContext(ctx).Errorf("error retrieving usage of %s : %v", clientID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := usage.Series{}
+	for rows.Next() {
+		var dbUsage usage.Usage
+		if err := rows.Scan(&dbUsage.Day, &dbUsage.Route, &dbUsage.Count); err != nil {
+			u.logger.WithContext(ctx).Errorf("error decoding usage of %s : %v", clientID, err)
+			return nil, err
+		}
+		if len(series) == 0 || series[len(series)-1].Day != dbUsage.Day {
+			series = append(series, usage.Point{Day: dbUsage.Day, Routes: map[string]int64{}})
+		}
+		point := &series[len(series)-1]
+		point.Count += dbUsage.Count
+		point.Routes[dbUsage.Route] += dbUsage.Count
+	}
+	if err := rows.Err(); err != nil {
+		u.logger.WithContext(ctx).Errorf("error decoding usage of %s : %v", clientID, err)
+		return nil, err
+	}
+
+	return series, nil
+}
+
+func (u *usageRepository) TopConsumers(ctx context.Context, from, to string, limit int) (usage.Consumers, error) {
+	rows, err := u.db.QueryContext(ctx, `SELECT client_id, SUM(count) AS total FROM api_key_usage
+		WHERE day BETWEEN ? AND ? GROUP BY client_id ORDER BY total DESC, client_id LIMIT ?`, from, to, limit)
+	if err != nil {
+		u.logger.WithContext(ctx).Errorf("error aggregating top consumers : %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	consumers := usage.Consumers{}
+	for rows.Next() {
+		var consumer usage.Consumer
+		if err := rows.Scan(&consumer.ClientID, &consumer.Count); err != nil {
+			u.logger.WithContext(ctx).Errorf("error decoding top consumers : %v", err)
+			return nil, err
+		}
+		consumers = append(consumers, consumer)
+	}
+	if err := rows.Err(); err != nil {
+		u.logger.WithContext(ctx).Errorf("error decoding top consumers : %v", err)
+		return nil, err
+	}
+
+	return consumers, nil
+}
@@ -0,0 +1,216 @@
+// Package repositorytest holds the conformance suite every backend of the
+// repository interfaces must pass.
+package repositorytest
+
+import (
+	"apikey/internal/errormap"
+	"apikey/internal/model/apikey"
+	"apikey/internal/model/company"
+	"apikey/internal/model/gracePeriod"
+	"apikey/internal/model/usage"
+	mysql "apikey/internal/repository"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Backend is a set of repositories sharing a storage, and how to store
+// fixtures in it.
+type Backend struct {
+	ApiKeys      mysql.ApiKeyRepository
+	Companies    mysql.CompanyRepository
+	Usage        mysql.UsageRepository
+	GracePeriods mysql.GracePeriodRepository
+	Health       mysql.HealthRepository
+
+	PutApiKey      func(t *testing.T, apiKey string, key apikey.ApiKey)
+	PutCompany     func(t *testing.T, c company.Company)
+	PutGracePeriod func(t *testing.T, rule gracePeriod.CommerceRule)
+}
+
+type suiteTest struct {
+	test      func(t *testing.T, b Backend)
+	supported func(b Backend) bool
+}
+
+// Run runs the suite, newBackend returns an empty backend for each test.
+// Tests of the repositories a backend leaves nil are skipped.
+func Run(t *testing.T, newBackend func(t *testing.T) Backend) {
+	var (
+		apiKeys      = func(b Backend) bool { return b.ApiKeys != nil }
+		companies    = func(b Backend) bool { return b.Companies != nil }
+		gracePeriods = func(b Backend) bool { return b.GracePeriods != nil }
+		usages       = func(b Backend) bool { return b.Usage != nil }
+		health       = func(b Backend) bool { return b.Health != nil }
+	)
+	tests := map[string]suiteTest{
+		"ApiKeyFound":        {testApiKeyFound, apiKeys},
+		"ApiKeyNotFound":     {testApiKeyNotFound, apiKeys},
+		"CompanyFound":       {testCompanyFound, companies},
+		"CompanyNotFound":    {testCompanyNotFound, companies},
+		"GracePeriodFound":   {testGracePeriodFound, gracePeriods},
+		"GracePeriodMissing": {testGracePeriodNotFound, gracePeriods},
+		"UsageSeries":        {testUsageSeries, usages},
+		"UsageTopConsumers":  {testUsageTopConsumers, usages},
+		"Ping":               {testPing, health},
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			b := newBackend(t)
+			if !test.supported(b) {
+				t.Skip("not implemented by this backend")
+			}
+			test.test(t, b)
+		})
+	}
+}
+
+func testApiKeyFound(t *testing.T, b Backend) {
+	want := apikey.ApiKey{
+		ClientID:       "client-1",
+		IsActive:       true,
+		ExpiredAt:      time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+		Type:           apikey.PublishableKey,
+		AllowedOrigins: []string{"https://shop.example.com"},
+		Environment:    "live",
+		CompanyID:      7,
+		Timezone:       "America/Mexico_City",
+		UsageLimits: apikey.UsageLimits{
+			Plan:          "premium",
+			DailyLimit:    100,
+			RatePerSecond: 5,
+			Burst:         10,
+		},
+	}
+	b.PutApiKey(t, "secret-1", want)
+	b.PutApiKey(t, "secret-2", apikey.ApiKey{ClientID: "client-2"})
+
+	got, err := b.ApiKeys.ValidateApiKey(context.Background(), "secret-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ClientID != want.ClientID || got.IsActive != want.IsActive || !got.ExpiredAt.Equal(want.ExpiredAt) ||
+		got.Type != want.Type || !reflect.DeepEqual(got.AllowedOrigins, want.AllowedOrigins) ||
+		got.Environment != want.Environment || got.CompanyID != want.CompanyID || got.Timezone != want.Timezone ||
+		got.UsageLimits != want.UsageLimits {
+		t.Errorf("ValidateApiKey = %+v, want %+v", got, want)
+	}
+}
+
+func testApiKeyNotFound(t *testing.T, b Backend) {
+	b.PutApiKey(t, "secret-1", apikey.ApiKey{ClientID: "client-1"})
+
+	_, err := b.ApiKeys.ValidateApiKey(context.Background(), "unknown")
+	if !errors.Is(err, errormap.ErrNoRows) {
+		t.Errorf("ValidateApiKey of an unknown key = %v, want ErrNoRows", err)
+	}
+}
+
+func testCompanyFound(t *testing.T, b Backend) {
+	b.PutCompany(t, company.Company{ID: 7, Name: "Tienda", Active: true, Timezone: "America/Bogota", Locale: "es"})
+
+	got, err := b.Companies.GetCompany(context.Background(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 7 || got.Name != "Tienda" || !got.Active || got.Timezone != "America/Bogota" || got.Locale != "es" {
+		t.Errorf("GetCompany = %+v", got)
+	}
+}
+
+func testCompanyNotFound(t *testing.T, b Backend) {
+	_, err := b.Companies.GetCompany(context.Background(), 404)
+	if !errors.Is(err, errormap.ErrCommerceNotFound) {
+		t.Errorf("GetCompany of an unknown company = %v, want ErrCommerceNotFound", err)
+	}
+}
+
+func testGracePeriodFound(t *testing.T, b Backend) {
+	b.PutGracePeriod(t, gracePeriod.CommerceRule{IdCommerce: "client-1", MaxAmount: 1500.5, LimitTransaction: 20, Category: "retail"})
+
+	got, err := b.GracePeriods.ValidationGracePeriod(context.Background(), "client-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.IdCommerce != "client-1" || got.MaxAmount != 1500.5 || got.LimitTransaction != 20 || got.Category != "retail" {
+		t.Errorf("ValidationGracePeriod = %+v", got)
+	}
+}
+
+func testGracePeriodNotFound(t *testing.T, b Backend) {
+	_, err := b.GracePeriods.ValidationGracePeriod(context.Background(), "unknown")
+	if !errors.Is(err, errormap.ErrNoRows) {
+		t.Errorf("ValidationGracePeriod of an unknown commerce = %v, want ErrNoRows", err)
+	}
+}
+
+func record(t *testing.T, b Backend, clientID, day, route string, times int) {
+	t.Helper()
+	for i := 0; i < times; i++ {
+		if err := b.Usage.Record(context.Background(), clientID, day, route, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testUsageSeries(t *testing.T, b Backend) {
+	record(t, b, "client-1", "2026-10-02", "GET /payments", 2)
+	record(t, b, "client-1", "2026-10-01", "GET /payments", 1)
+	record(t, b, "client-1", "2026-10-01", "POST /payments", 3)
+	record(t, b, "client-1", "2026-10-05", "GET /payments", 1)
+	record(t, b, "client-2", "2026-10-01", "GET /payments", 4)
+
+	got, err := b.Usage.Series(context.Background(), "client-1", "2026-10-01", "2026-10-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := usage.Series{
+		{Day: "2026-10-01", Count: 4, Routes: map[string]int64{"GET /payments": 1, "POST /payments": 3}},
+		{Day: "2026-10-02", Count: 2, Routes: map[string]int64{"GET /payments": 2}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Series = %+v, want %+v", got, want)
+	}
+
+	empty, err := b.Usage.Series(context.Background(), "client-3", "2026-10-01", "2026-10-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty == nil || len(empty) != 0 {
+		t.Errorf("Series of a client without usage = %#v, want an empty series", empty)
+	}
+}
+
+func testUsageTopConsumers(t *testing.T, b Backend) {
+	record(t, b, "client-a", "2026-10-01", "GET /payments", 2)
+	record(t, b, "client-b", "2026-10-01", "GET /payments", 3)
+	record(t, b, "client-b", "2026-10-02", "POST /payments", 1)
+	record(t, b, "client-c", "2026-10-02", "GET /payments", 2)
+	record(t, b, "client-d", "2026-11-01", "GET /payments", 9)
+
+	got, err := b.Usage.TopConsumers(context.Background(), "2026-10-01", "2026-10-31", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := usage.Consumers{{ClientID: "client-b", Count: 4}, {ClientID: "client-a", Count: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TopConsumers = %+v, want %+v", got, want)
+	}
+
+	empty, err := b.Usage.TopConsumers(context.Background(), "2025-01-01", "2025-01-31", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty == nil || len(empty) != 0 {
+		t.Errorf("TopConsumers without usage = %#v, want no consumers", empty)
+	}
+}
+
+func testPing(t *testing.T, b Backend) {
+	if err := b.Health.Ping(context.Background()); err != nil {
+		t.Errorf("Ping = %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package mongodb
+
+import (
+	"apikey/internal/model/apikey"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// legacyApiKeyVersion is the version of the api key documents written before
+// they carried a schemaVersion. ApiKey had no bson tags then, the driver
+// stored its fields lowercased and matched them case insensitively on reads.
+const legacyApiKeyVersion = 1
+
+// apikeyUpgraders turn an api key document of the version of their key into
+// the next version, every version below SchemaVersion needs one.
+var apikeyUpgraders = map[int]func(doc bson.M){
+	1: upgradeApiKeyV1,
+	2: upgradeApiKeyV2,
+}
+
+var (
+	apikeyFieldsV2 = []string{
+		"platformData", "expiredAt", "isActive", "requestCount", "usageLimits", "clientId",
+		"type", "allowedOrigins", "schedules", "environment", "stages", "companyId", "timezone",
+	}
+	usageLimitsFieldsV2 = []string{"plan", "dailyLimit", "monthlyLimit", "limit", "ratePerSecond", "burst"}
+)
+
+// upgradeApiKeyV1 renames the fields to the names of the bson tags.
+func upgradeApiKeyV1(doc bson.M) {
+	renameFields(doc, apikeyFieldsV2)
+	if limits, ok := doc["usageLimits"].(bson.M); ok {
+		renameFields(limits, usageLimitsFieldsV2)
+	}
+}
+
+// upgradeApiKeyV2 replaces the api key by its hash.
+func upgradeApiKeyV2(doc bson.M) {
+	if key, ok := doc["apiKey"].(string); ok {
+		doc["apiKeyHash"] = apikey.Hash(key)
+		delete(doc, "apiKey")
+	}
+}
+
+// renameFields renames the keys of doc that match one of names case
+// insensitively to that name.
+func renameFields(doc bson.M, names []string) {
+	for _, name := range names {
+		if _, ok := doc[name]; ok {
+			continue
+		}
+		for key, value := range doc {
+			if strings.EqualFold(key, name) {
+				delete(doc, key)
+				doc[name] = value
+				break
+			}
+		}
+	}
+}
+
+// apikeyVersion returns the schemaVersion of doc, legacy documents have none.
+func apikeyVersion(doc bson.M) int {
+	var version int
+	switch v := doc["schemaVersion"].(type) {
+	case int32:
+		version = int(v)
+	case int64:
+		version = int(v)
+	case float64:
+		version = int(v)
+	}
+	if version < legacyApiKeyVersion {
+		return legacyApiKeyVersion
+	}
+	return version
+}
+
+// UpgradeApiKey brings doc up to SchemaVersion in place and reports whether
+// it changed. Documents of a newer version are left as they are.
+func UpgradeApiKey(doc bson.M) bool {
+	version := apikeyVersion(doc)
+	if version >= SchemaVersion {
+		return false
+	}
+
+	for ; version < SchemaVersion; version++ {
+		apikeyUpgraders[version](doc)
+	}
+	doc["schemaVersion"] = int32(SchemaVersion)
+	return true
+}
+
+// DecodeApiKey reads an api key document of any version.
+func DecodeApiKey(raw bson.Raw) (*apikey.ApiKey, error) {
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("error decoding ApiKey : %w", err)
+	}
+
+	if UpgradeApiKey(doc) {
+		data, err := bson.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding ApiKey : %w", err)
+		}
+		raw = data
+	}
+
+	var key apikey.ApiKey
+	if err := bson.Unmarshal(raw, &key); err != nil {
+		return nil, fmt.Errorf("error decoding ApiKey : %w", err)
+	}
+	return &key, nil
+}
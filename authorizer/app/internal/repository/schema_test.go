@@ -0,0 +1,117 @@
+package mongodb_test
+
+import (
+	"apikey/internal/model/apikey"
+	mongodb "apikey/internal/repository"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// legacyApiKey is an api key as the driver stored it without bson tags.
+func legacyApiKey(t *testing.T) bson.Raw {
+	t.Helper()
+	raw, err := bson.Marshal(bson.D{
+		{Key: "apiKey", Value: "key-1"},
+		{Key: "platformdata", Value: bson.M{"commerceId": "10"}},
+		{Key: "expiredat", Value: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Key: "isactive", Value: true},
+		{Key: "usagelimits", Value: bson.D{
+			{Key: "plan", Value: "gold"},
+			{Key: "dailylimit", Value: 100},
+			{Key: "ratepersecond", Value: 2.5},
+		}},
+		{Key: "clientid", Value: "client-1"},
+		{Key: "allowedorigins", Value: bson.A{"https://example.com"}},
+		{Key: "schedules", Value: bson.A{bson.D{{Key: "timezone", Value: "UTC"}, {Key: "start", Value: "08:00"}}}},
+		{Key: "companyid", Value: int64(7)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestDecodeApiKeyLegacy(t *testing.T) {
+	key, err := mongodb.DecodeApiKey(legacyApiKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := apikey.ApiKey{
+		PlatformData:   map[string]interface{}{"commerceId": "10"},
+		ExpiredAt:      time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		IsActive:       true,
+		UsageLimits:    apikey.UsageLimits{Plan: "gold", DailyLimit: 100, RatePerSecond: 2.5},
+		ClientID:       "client-1",
+		AllowedOrigins: []string{"https://example.com"},
+		Schedules:      []apikey.AccessSchedule{{Timezone: "UTC", Start: "08:00"}},
+		CompanyID:      7,
+		SchemaVersion:  mongodb.SchemaVersion,
+	}
+	key.ExpiredAt = key.ExpiredAt.UTC()
+	if !reflect.DeepEqual(*key, want) {
+		t.Errorf("expected %+v, got %+v", want, *key)
+	}
+}
+
+func TestDecodeApiKeyCurrent(t *testing.T) {
+	want := apikey.ApiKey{
+		IsActive:      true,
+		UsageLimits:   apikey.UsageLimits{Plan: "gold", MonthlyLimit: 10},
+		ClientID:      "client-1",
+		Stages:        []string{"prod"},
+		SchemaVersion: mongodb.SchemaVersion,
+	}
+	raw, err := bson.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := mongodb.DecodeApiKey(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.ExpiredAt = want.ExpiredAt
+	if !reflect.DeepEqual(*key, want) {
+		t.Errorf("expected %+v, got %+v", want, *key)
+	}
+}
+
+func TestUpgradeApiKey(t *testing.T) {
+	var doc bson.M
+	if err := bson.Unmarshal(legacyApiKey(t), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if !mongodb.UpgradeApiKey(doc) {
+		t.Fatal("expected the legacy document to be upgraded")
+	}
+	for _, field := range []string{"clientId", "isActive", "usageLimits", "companyId", "apiKeyHash"} {
+		if _, ok := doc[field]; !ok {
+			t.Errorf("expected field %s in %v", field, doc)
+		}
+	}
+	if _, ok := doc["clientid"]; ok {
+		t.Errorf("expected legacy field clientid to be renamed")
+	}
+	if _, ok := doc["apiKey"]; ok || doc["apiKeyHash"] != apikey.Hash("key-1") {
+		t.Errorf("expected the api key to be replaced by its hash, got %v", doc["apiKeyHash"])
+	}
+	if limits := doc["usageLimits"].(bson.M); limits["dailyLimit"] == nil {
+		t.Errorf("expected usageLimits.dailyLimit in %v", limits)
+	}
+	if doc["schemaVersion"] != int32(mongodb.SchemaVersion) {
+		t.Errorf("expected schemaVersion %d, got %v", mongodb.SchemaVersion, doc["schemaVersion"])
+	}
+
+	if mongodb.UpgradeApiKey(doc) {
+		t.Error("expected an upgraded document to be left as it is")
+	}
+	doc["schemaVersion"] = int32(mongodb.SchemaVersion + 1)
+	if mongodb.UpgradeApiKey(doc) {
+		t.Error("expected a newer document to be left as it is")
+	}
+}
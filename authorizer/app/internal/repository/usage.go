@@ -0,0 +1,100 @@
+package mongodb
+
+import (
+	"apikey/internal/model/usage"
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UsageRepository interface {
+	// Record counts a call of clientID to route on day, made at at.
+	Record(ctx context.Context, clientID, day, route string, at time.Time) error
+	Series(ctx context.Context, clientID, from, to string) (usage.Series, error)
+	TopConsumers(ctx context.Context, from, to string, limit int) (usage.Consumers, error)
+}
+
+type usageRepository struct {
+	logger     *logrus.Logger
+	collection *mongo.Collection
+}
+
+func NewUsageRepository(logger *logrus.Logger, db *mongo.Client, dbName, collectionName string) UsageRepository {
+	collection := db.Database(dbName).Collection(collectionName)
+	return &usageRepository{
+		logger:     logger,
+		collection: collection,
+	}
+}
+
+func (u *usageRepository) Record(ctx context.Context, clientID, day, route string, at time.Time) error {
+	filter := bson.M{"clientId": clientID, "day": day, "route": route}
+	update := bson.M{
+		"$inc": bson.M{"count": 1},
+		"$set": bson.M{"updatedAt": at},
+	}
+
+	_, err := u.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		u.logger.WithContext(ctx).Errorf("error recording usage of %s : %v", clientID, err)
+		return err
+	}
+
+	return nil
+}
+
+// Series returns one point per day with calls, days are YYYY-MM-DD so they
+// compare as strings.
+func (u *usageRepository) Series(ctx context.Context, clientID, from, to string) (usage.Series, error) {
+	filter := bson.M{"clientId": clientID, "day": bson.M{"$gte": from, "$lte": to}}
+	cursor, err := u.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "day", Value: 1}}))
+	if err != nil {
+		u.logger.WithContext(ctx).Errorf("error retrieving usage of %s : %v", clientID, err)
+		return nil, err
+	}
+
+	var usages usage.Usages
+	if err := cursor.All(ctx, &usages); err != nil {
+		u.logger.WithContext(ctx).Errorf("error decoding usage of %s : %v", clientID, err)
+		return nil, err
+	}
+
+	series := usage.Series{}
+	for _, dbUsage := range usages {
+		if len(series) == 0 || series[len(series)-1].Day != dbUsage.Day {
+			series = append(series, usage.Point{Day: dbUsage.Day, Routes: map[string]int64{}})
+		}
+		point := &series[len(series)-1]
+		point.Count += dbUsage.Count
+		point.Routes[dbUsage.Route] += dbUsage.Count
+	}
+
+	return series, nil
+}
+
+func (u *usageRepository) TopConsumers(ctx context.Context, from, to string, limit int) (usage.Consumers, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"day": bson.M{"$gte": from, "$lte": to}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$clientId", "count": bson.M{"$sum": "$count"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	cursor, err := u.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		u.logger.WithContext(ctx).Errorf("error aggregating top consumers : %v", err)
+		return nil, err
+	}
+
+	consumers := usage.Consumers{}
+	if err := cursor.All(ctx, &consumers); err != nil {
+		u.logger.WithContext(ctx).Errorf("error decoding top consumers : %v", err)
+		return nil, err
+	}
+
+	return consumers, nil
+}
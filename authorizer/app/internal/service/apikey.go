@@ -3,28 +3,147 @@ package service
 import (
 	"apikey/internal/errormap"
 	"apikey/internal/model/apikey"
+	"apikey/internal/model/usage"
+	"apikey/internal/notify"
+	"apikey/internal/ratelimit"
+	"apikey/internal/snapshot"
+	"apikey/pkg/clock"
 	"apikey/pkg/errorx"
+	"apikey/pkg/metrics"
+	"apikey/pkg/tracing"
 
 	mysql "apikey/internal/repository"
 	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ServiceApiKey interface {
-	ValidateApiKey(ctx context.Context, apikey string) (*apikey.ApiKey, error)
+	ValidateApiKey(ctx context.Context, access apikey.Access) (*apikey.ApiKey, error)
+	// Flush waits for the work a validation left in the background.
+	Flush(ctx context.Context) error
+	CacheReporter
 }
 
 type serviceApiKey struct {
-	logger           *logrus.Logger
-	apikeyRepository mysql.ApiKeyRepository
+	logger                *logrus.Logger
+	apikeyRepository      mysql.ApiKeyRepository
+	clock                 clock.Clock
+	limiter               ratelimit.Limiter
+	counter               ratelimit.Counter
+	companyRepository     mysql.CompanyRepository
+	companies             companyCache
+	rules                 ruleCache
+	usageRepository       mysql.UsageRepository
+	gracePeriodRepository mysql.GracePeriodRepository
+	planPolicies          map[string]apikey.PlanPolicy
+	notifier              notify.Notifier
+	alerts                sync.WaitGroup
+	failPolicy            FailPolicy
+	snapshot              *snapshot.Store
+	metrics               metrics.Metrics
+	tracer                trace.Tracer
 }
 
-func NewApiKeyService(logger *logrus.Logger, apikeyRepository mysql.ApiKeyRepository) ServiceApiKey {
-	return &serviceApiKey{
+type Option func(*serviceApiKey)
+
+func WithClock(clock clock.Clock) Option {
+	return func(s *serviceApiKey) {
+		s.clock = clock
+	}
+}
+
+func WithLimiter(limiter ratelimit.Limiter) Option {
+	return func(s *serviceApiKey) {
+		s.limiter = limiter
+	}
+}
+
+func WithCounter(counter ratelimit.Counter) Option {
+	return func(s *serviceApiKey) {
+		s.counter = counter
+	}
+}
+
+func WithCompanyRepository(companyRepository mysql.CompanyRepository) Option {
+	return func(s *serviceApiKey) {
+		s.companyRepository = companyRepository
+	}
+}
+
+func WithUsageRepository(usageRepository mysql.UsageRepository) Option {
+	return func(s *serviceApiKey) {
+		s.usageRepository = usageRepository
+	}
+}
+
+func WithGracePeriodRepository(gracePeriodRepository mysql.GracePeriodRepository) Option {
+	return func(s *serviceApiKey) {
+		s.gracePeriodRepository = gracePeriodRepository
+	}
+}
+
+func WithPlanPolicies(planPolicies map[string]apikey.PlanPolicy) Option {
+	return func(s *serviceApiKey) {
+		s.planPolicies = planPolicies
+	}
+}
+
+func WithNotifier(notifier notify.Notifier) Option {
+	return func(s *serviceApiKey) {
+		s.notifier = notifier
+	}
+}
+
+func WithFailPolicy(failPolicy FailPolicy, snapshot *snapshot.Store) Option {
+	return func(s *serviceApiKey) {
+		s.failPolicy = failPolicy
+		s.snapshot = snapshot
+	}
+}
+
+func WithMetrics(metrics metrics.Metrics) Option {
+	return func(s *serviceApiKey) {
+		s.metrics = metrics
+	}
+}
+
+func WithTracer(tracer trace.Tracer) Option {
+	return func(s *serviceApiKey) {
+		s.tracer = tracer
+	}
+}
+
+func NewApiKeyService(logger *logrus.Logger, apikeyRepository mysql.ApiKeyRepository, options ...Option) ServiceApiKey {
+	s := &serviceApiKey{
 		logger:           logger,
 		apikeyRepository: apikeyRepository,
+		clock:            clock.System,
+		metrics:          metrics.Nop,
+		tracer:           tracing.Nop,
+		companies:        companyCache{companies: map[int64]cachedCompany{}},
+		rules:            ruleCache{rules: map[string]cachedRule{}},
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	if s.limiter == nil {
+		s.limiter = ratelimit.NewMemoryLimiter(s.clock)
+	}
+	if s.counter == nil {
+		s.counter = ratelimit.NewMemoryCounter(s.clock)
 	}
+
+	return s
 }
 
 var (
@@ -32,19 +151,158 @@ var (
 	ErrInvalidEmail    = errorx.NewErrorf(errormap.CodeInvalidArgument, "invalid commerce")
 )
 
-func (s *serviceApiKey) ValidateApiKey(ctx context.Context, id string) (*apikey.ApiKey, error) {
-	resp, err := s.apikeyRepository.ValidateApiKey(ctx, id)
+func (s *serviceApiKey) ValidateApiKey(ctx context.Context, access apikey.Access) (key *apikey.ApiKey, err error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceApiKey.ValidateApiKey", trace.WithAttributes(
+		attribute.String("apikey.route", access.Route),
+		attribute.String("apikey.stage", access.Stage),
+	))
+	defer func() {
+		if key != nil {
+			span.SetAttributes(attribute.String("apikey.client_id", key.ClientID))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if access.ApiKey == "" {
+		return &apikey.ApiKey{}, errormap.ErrMissingKey
+	}
+
+	resp, err := s.apikeyRepository.ValidateApiKey(ctx, access.ApiKey)
+	if err == nil && s.snapshot != nil {
+		s.snapshot.Put(access.ApiKey, resp)
+	}
+	if err != nil && !errors.Is(err, errormap.ErrNoRows) {
+		resp, err = s.fallback(ctx, access.ApiKey, err)
+	}
 	if err != nil {
-		s.logger.Errorf("Error getting apikey: %v", err)
+		s.logger.WithContext(ctx).Errorf("Error getting apikey: %v", err)
 		return &apikey.ApiKey{}, err
 	}
 
-	err = resp.Validate()
+	err = resp.Validate(s.clock.Now())
 
 	if err != nil {
-		s.logger.Errorf("Invalid apikey: %v", err)
-		return &apikey.ApiKey{}, err
+		s.logger.WithContext(ctx).Errorf("Invalid apikey: %v", err)
+		return s.deny(ctx, resp, err)
+	}
+
+	if !resp.AllowsOrigin(access.Origin, access.Referer) {
+		s.logger.WithContext(ctx).Errorf("Publishable apikey %s used from origin %q referer %q", resp.ClientID, access.Origin, access.Referer)
+		return s.deny(ctx, resp, errormap.ErrOriginNotAllowed)
 	}
 
+	if !resp.AllowsMethod(access.Method) {
+		s.logger.WithContext(ctx).Errorf("Publishable apikey %s used for %s", resp.ClientID, access.Method)
+		return s.deny(ctx, resp, errormap.ErrReadOnlyKey)
+	}
+
+	if !resp.AllowsStage(access.Stage) {
+		if access.Stage == "" {
+			s.logger.WithContext(ctx).Errorf("Apikey %s of environment %q used on an unknown stage", resp.ClientID, resp.Environment)
+		} else {
+			s.logger.WithContext(ctx).Infof("Apikey %s of environment %q used on stage %q", resp.ClientID, resp.Environment, access.Stage)
+		}
+		return s.deny(ctx, resp, &apikey.DenyError{
+			Err:     errormap.ErrWrongEnvironment,
+			Context: map[string]interface{}{"testKey": resp.IsTestKey()},
+		})
+	}
+
+	open, next, err := resp.OpenAt(s.clock.Now())
+	if err != nil {
+		s.logger.WithContext(ctx).Errorf("Invalid schedule for apikey %s: %v", resp.ClientID, err)
+		return s.deny(ctx, resp, errormap.ErrInvalidKeyConfig)
+	}
+	if !open {
+		s.logger.WithContext(ctx).Infof("Apikey %s used outside its schedule", resp.ClientID)
+		deny := &apikey.DenyError{Err: errormap.ErrOutsideSchedule, Context: map[string]interface{}{}}
+		if !next.IsZero() {
+			deny.Context["nextOpening"] = next.Format(time.RFC3339)
+		}
+		return s.deny(ctx, resp, deny)
+	}
+
+	if err := s.checkRate(ctx, resp); err != nil {
+		return s.deny(ctx, resp, err)
+	}
+
+	if err := s.checkQuota(ctx, resp); err != nil {
+		return s.deny(ctx, resp, err)
+	}
+
+	if err := s.checkTransaction(ctx, resp, access); err != nil {
+		s.releaseQuota(ctx, resp)
+		return s.deny(ctx, resp, err)
+	}
+
+	s.recordUsage(ctx, resp, access)
+
 	return resp, nil
 }
+
+// deny keeps the client, limits and company locale of a key that was found
+// but is not allowed, so the Deny is worded in the company's language and
+// counted for the client. Once the quota was evaluated the deny also carries
+// its state, the counters are not read again for it.
+func (s *serviceApiKey) deny(ctx context.Context, key *apikey.ApiKey, err error) (*apikey.ApiKey, error) {
+	denied := &apikey.ApiKey{
+		ClientID:    key.ClientID,
+		UsageLimits: key.UsageLimits,
+		Locale:      s.locale(ctx, key),
+		Quotas:      key.Quotas,
+	}
+	return denied, err
+}
+
+// checkRate takes a token from the key's bucket, a limiter failure lets the
+// call through instead of locking every key out.
+func (s *serviceApiKey) checkRate(ctx context.Context, key *apikey.ApiKey) error {
+	limit := ratelimit.Limit{
+		Rate:  key.UsageLimits.RatePerSecond,
+		Burst: key.UsageLimits.Burst,
+	}
+	if !limit.Enabled() {
+		return nil
+	}
+
+	result, err := s.limiter.Allow(ctx, key.ClientID, limit)
+	if err != nil {
+		s.logger.WithContext(ctx).Errorf("Error checking rate limit of apikey %s: %v", key.ClientID, err)
+		return nil
+	}
+	if result.Allowed {
+		return nil
+	}
+
+	s.logger.WithContext(ctx).Infof("Apikey %s rate limited, retry after %v", key.ClientID, result.RetryAfter)
+	return &apikey.DenyError{
+		Err: errormap.ErrRateLimited,
+		Context: map[string]interface{}{
+			"retryAfter": int(math.Ceil(result.RetryAfter.Seconds())),
+		},
+	}
+}
+
+// recordUsage meters the allowed call for the usage reports, the day is the
+// one of the key's timezone. Failing to record does not deny the call. Calls
+// without a resource template are counted under usage.UntemplatedRoute.
+func (s *serviceApiKey) recordUsage(ctx context.Context, key *apikey.ApiKey, access apikey.Access) {
+	if s.usageRepository == nil {
+		return
+	}
+
+	route := access.Route
+	if !access.RouteTemplate {
+		route = access.Method + " " + usage.UntemplatedRoute
+	}
+
+	now := s.clock.Now()
+	day := now.In(s.location(ctx, key)).Format(usage.DayLayout)
+	if err := s.usageRepository.Record(ctx, key.ClientID, day, route, now); err != nil {
+		s.logger.WithContext(ctx).Errorf("Error recording usage of apikey %s: %v", key.ClientID, err)
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"apikey/internal/errormap"
+	"apikey/internal/model/apikey"
+	"apikey/internal/repository/memory"
+	"apikey/internal/snapshot"
+	applog "apikey/pkg/logger"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type manualClock struct {
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	return c.now
+}
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func newTestService(clock *manualClock, options ...Option) (ServiceApiKey, *memory.ApiKeyRepository) {
+	repository := memory.NewApiKeyRepository()
+	repository.Put("active", apikey.ApiKey{ClientID: "client-1", IsActive: true, ExpiredAt: clock.now.Add(24 * time.Hour)})
+	repository.Put("inactive", apikey.ApiKey{ClientID: "client-2", IsActive: false, ExpiredAt: clock.now.Add(24 * time.Hour)})
+
+	options = append([]Option{WithClock(clock), WithUsageRepository(memory.NewUsageRepository())}, options...)
+	return NewApiKeyService(newTestLogger(), repository, options...), repository
+}
+
+func TestValidateApiKey(t *testing.T) {
+	s, _ := newTestService(&manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)})
+
+	tests := map[string]error{
+		"active":   nil,
+		"inactive": errormap.ErrKeyInactive,
+		"unknown":  errormap.ErrNoRows,
+		"":         errormap.ErrMissingKey,
+	}
+	for key, want := range tests {
+		_, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: key})
+		if !errors.Is(err, want) || (want == nil && err != nil) {
+			t.Errorf("ValidateApiKey(%q) = %v, want %v", key, err, want)
+		}
+	}
+}
+
+func TestValidateApiKeyFailStatic(t *testing.T) {
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	store := snapshot.New(newTestLogger(), clock, "")
+	s, repository := newTestService(clock, WithFailPolicy(FailPolicy{Mode: FailStatic, MaxStaleness: 10 * time.Minute}, store))
+
+	if _, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "active"}); err != nil {
+		t.Fatal(err)
+	}
+
+	down := errors.New("server selection timeout")
+	repository.Fail(down)
+
+	clock.now = clock.now.Add(5 * time.Minute)
+	key, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "active"})
+	if err != nil {
+		t.Fatalf("fresh snapshot not served: %v", err)
+	}
+	if key.ClientID != "client-1" || key.FailMode != FailStatic || key.Staleness != 5*time.Minute {
+		t.Errorf("served %+v", key)
+	}
+
+	if _, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "inactive"}); !errors.Is(err, down) {
+		t.Errorf("key missing from the snapshot = %v, want the database error", err)
+	}
+
+	clock.now = clock.now.Add(10 * time.Minute)
+	if _, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "active"}); !errors.Is(err, down) {
+		t.Errorf("stale snapshot = %v, want the database error", err)
+	}
+}
+
+func TestValidateApiKeyLogsRequestID(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.AddHook(applog.ContextHook{})
+	repository := memory.NewApiKeyRepository()
+	repository.Fail(errors.New("server selection timeout"))
+	s := NewApiKeyService(logger, repository)
+
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "request-1"})
+	if _, err := s.ValidateApiKey(ctx, apikey.Access{ApiKey: "key"}); err == nil {
+		t.Fatal("expected the database error")
+	}
+
+	if len(hook.AllEntries()) == 0 {
+		t.Fatal("nothing logged")
+	}
+	for _, entry := range hook.AllEntries() {
+		if entry.Data[applog.RequestIDField] != "request-1" {
+			t.Errorf("entry %q has no request id: %v", entry.Message, entry.Data)
+		}
+	}
+}
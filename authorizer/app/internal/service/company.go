@@ -0,0 +1,109 @@
+package service
+
+import (
+	"apikey/internal/model/apikey"
+	"apikey/internal/model/company"
+	"apikey/pkg/metrics"
+	"context"
+	"sync"
+	"time"
+)
+
+// companyTTL bounds how long company settings are reused between calls.
+const companyTTL = 5 * time.Minute
+
+type cachedCompany struct {
+	company   *company.Company
+	expiresAt time.Time
+}
+
+type companyCache struct {
+	mu        sync.Mutex
+	companies map[int64]cachedCompany
+}
+
+// company returns the settings of the key's company, nil when the key has
+// none or they cannot be read.
+func (s *serviceApiKey) company(ctx context.Context, key *apikey.ApiKey) *company.Company {
+	if s.companyRepository == nil || key.CompanyID == 0 {
+		return nil
+	}
+
+	now := s.clock.Now()
+	s.companies.mu.Lock()
+	cached, ok := s.companies.companies[key.CompanyID]
+	s.companies.mu.Unlock()
+	hit := ok && now.Before(cached.expiresAt)
+	s.metrics.Put(metrics.CacheHit, hitValue(hit), metrics.None, metrics.Dimensions{metrics.Cache: "company"})
+	if hit {
+		return cached.company
+	}
+
+	c, err := s.companyRepository.GetCompany(ctx, key.CompanyID)
+	if err != nil {
+		s.logger.WithContext(ctx).Errorf("Error getting company %d of apikey %s: %v", key.CompanyID, key.ClientID, err)
+		return nil
+	}
+
+	s.companies.mu.Lock()
+	s.companies.companies[key.CompanyID] = cachedCompany{company: c, expiresAt: now.Add(companyTTL)}
+	s.companies.mu.Unlock()
+
+	return c
+}
+
+// CacheStatus reports the companies and commerce rules cached and the keys
+// of the last known good snapshot.
+func (s *serviceApiKey) CacheStatus() map[string]int {
+	s.companies.mu.Lock()
+	status := map[string]int{"companies": len(s.companies.companies)}
+	s.companies.mu.Unlock()
+
+	s.rules.mu.Lock()
+	status["commerceRules"] = len(s.rules.rules)
+	s.rules.mu.Unlock()
+
+	if s.snapshot != nil {
+		status["snapshot"] = s.snapshot.Len()
+	}
+	return status
+}
+
+// hitValue is 1 for a hit and 0 for a miss, so the average of CacheHit is
+// the hit ratio.
+func hitValue(hit bool) float64 {
+	if hit {
+		return 1
+	}
+	return 0
+}
+
+// location is the timezone quotas of the key reset in, the key's own, else
+// its company's, else UTC.
+func (s *serviceApiKey) location(ctx context.Context, key *apikey.ApiKey) *time.Location {
+	name := key.Timezone
+	if name == "" {
+		if c := s.company(ctx, key); c != nil {
+			name = c.Timezone
+		}
+	}
+	if name == "" {
+		return time.UTC
+	}
+
+	location, err := apikey.LoadLocation(name)
+	if err != nil {
+		s.logger.WithContext(ctx).Errorf("Invalid timezone %q for apikey %s: %v", name, key.ClientID, err)
+		return time.UTC
+	}
+	return location
+}
+
+// locale is the language the company of the key chose for messages, empty
+// when it has none.
+func (s *serviceApiKey) locale(ctx context.Context, key *apikey.ApiKey) string {
+	if c := s.company(ctx, key); c != nil {
+		return c.Locale
+	}
+	return ""
+}
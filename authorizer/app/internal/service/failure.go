@@ -0,0 +1,78 @@
+package service
+
+import (
+	"apikey/internal/model/apikey"
+	"context"
+	"time"
+)
+
+const (
+	// FailClosed denies every call while the database is down.
+	FailClosed = "closed"
+	// FailStatic serves keys from the last known good snapshot while it is
+	// not older than MaxStaleness.
+	FailStatic = "static"
+	// FailOpen serves keys from the snapshot like FailStatic, stale keys
+	// only when their client id or key fingerprint is one of the
+	// OpenClients, and allows unknown keys whose fingerprint is one of them.
+	FailOpen = "open"
+)
+
+// FailPolicy decides what happens when the key cannot be read.
+type FailPolicy struct {
+	Mode         string
+	MaxStaleness time.Duration
+	OpenClients  []string
+}
+
+// fallback answers a lookup that failed with err from the snapshot,
+// according to the fail policy. The key it returns records how it was
+// served so the decision and its staleness reach the context and logs.
+func (s *serviceApiKey) fallback(ctx context.Context, apiKey string, err error) (*apikey.ApiKey, error) {
+	if s.snapshot == nil || s.failPolicy.Mode == "" || s.failPolicy.Mode == FailClosed {
+		return nil, err
+	}
+
+	fingerprint := apikey.Fingerprint(apiKey)
+	key, staleness, found := s.snapshot.Get(apiKey)
+
+	switch s.failPolicy.Mode {
+	case FailStatic:
+		if !found || staleness > s.failPolicy.MaxStaleness {
+			s.logger.WithContext(ctx).Errorf("Fail static: no fresh snapshot of apikey %s (found %v, staleness %v): %v", fingerprint, found, staleness, err)
+			return nil, err
+		}
+	case FailOpen:
+		if !found {
+			if !s.openClient(fingerprint) {
+				s.logger.WithContext(ctx).Errorf("Fail open: apikey %s is not whitelisted: %v", fingerprint, err)
+				return nil, err
+			}
+			key = &apikey.ApiKey{
+				ClientID:  fingerprint,
+				IsActive:  true,
+				ExpiredAt: s.clock.Now().Add(time.Hour),
+			}
+		} else if staleness > s.failPolicy.MaxStaleness && !s.openClient(key.ClientID) && !s.openClient(fingerprint) {
+			s.logger.WithContext(ctx).Errorf("Fail open: stale apikey %s is not whitelisted (staleness %v): %v", fingerprint, staleness, err)
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).Warnf("Fail %s: serving apikey %s of %s from snapshot (staleness %v) after: %v",
+		s.failPolicy.Mode, fingerprint, key.ClientID, staleness, err)
+	key.FailMode = s.failPolicy.Mode
+	key.Staleness = staleness
+	return key, nil
+}
+
+func (s *serviceApiKey) openClient(id string) bool {
+	for _, client := range s.failPolicy.OpenClients {
+		if client == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"apikey/internal/model/apikey"
+	"apikey/internal/snapshot"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newFailService(t *testing.T, clock *manualClock, policy FailPolicy) (ServiceApiKey, *snapshot.Store, error) {
+	t.Helper()
+	store := snapshot.New(newTestLogger(), clock, "")
+	s, repository := newTestService(clock, WithFailPolicy(policy, store))
+	down := errors.New("server selection timeout")
+	repository.Fail(down)
+	return s, store, down
+}
+
+func TestFallbackFailOpen(t *testing.T) {
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	policy := FailPolicy{
+		Mode:         FailOpen,
+		MaxStaleness: 10 * time.Minute,
+		OpenClients:  []string{"client-open", apikey.Fingerprint("unknown-open")},
+	}
+	s, store, down := newFailService(t, clock, policy)
+	expiredAt := clock.now.Add(24 * time.Hour)
+	store.Put("known", &apikey.ApiKey{ClientID: "client-known", IsActive: true, ExpiredAt: expiredAt})
+	store.Put("known-open", &apikey.ApiKey{ClientID: "client-open", IsActive: true, ExpiredAt: expiredAt})
+
+	clock.now = clock.now.Add(5 * time.Minute)
+	key, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "known"})
+	if err != nil {
+		t.Fatalf("fresh known key = %v", err)
+	}
+	if key.ClientID != "client-known" || key.FailMode != FailOpen || key.Staleness != 5*time.Minute {
+		t.Errorf("served %+v", key)
+	}
+
+	key, err = s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "unknown-open"})
+	if err != nil {
+		t.Fatalf("whitelisted unknown key = %v", err)
+	}
+	if key.ClientID != apikey.Fingerprint("unknown-open") || key.FailMode != FailOpen {
+		t.Errorf("served %+v", key)
+	}
+
+	if _, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "unknown"}); !errors.Is(err, down) {
+		t.Errorf("unknown key = %v, want the database error", err)
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	if _, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "known"}); !errors.Is(err, down) {
+		t.Errorf("stale known key = %v, want the database error", err)
+	}
+	key, err = s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "known-open"})
+	if err != nil {
+		t.Fatalf("stale whitelisted key = %v", err)
+	}
+	if key.ClientID != "client-open" || key.Staleness != time.Hour+5*time.Minute {
+		t.Errorf("served %+v", key)
+	}
+}
+
+func TestFallbackFailClosed(t *testing.T) {
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	for _, mode := range []string{"", FailClosed} {
+		policy := FailPolicy{Mode: mode, MaxStaleness: time.Hour, OpenClients: []string{"client-known", apikey.Fingerprint("unknown")}}
+		s, store, down := newFailService(t, clock, policy)
+		store.Put("known", &apikey.ApiKey{ClientID: "client-known", IsActive: true, ExpiredAt: clock.now.Add(time.Hour)})
+
+		for _, value := range []string{"known", "unknown"} {
+			if _, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: value}); !errors.Is(err, down) {
+				t.Errorf("mode %q, key %s = %v, want the database error", mode, value, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"apikey/internal/model/health"
+	mysql "apikey/internal/repository"
+	"apikey/pkg/buildinfo"
+	"apikey/pkg/clock"
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// pingTimeout keeps a health check from waiting on an unreachable database
+// for the whole invocation.
+const pingTimeout = 2 * time.Second
+
+type ServiceHealth interface {
+	Check(ctx context.Context) health.Report
+}
+
+// CacheReporter tells how many entries a cache holds, by cache.
+type CacheReporter interface {
+	CacheStatus() map[string]int
+}
+
+type serviceHealth struct {
+	logger           *logrus.Logger
+	healthRepository mysql.HealthRepository
+	caches           []CacheReporter
+	configVersion    string
+	clock            clock.Clock
+}
+
+type HealthOption func(*serviceHealth)
+
+// WithCaches adds caches to the report.
+func WithCaches(caches ...CacheReporter) HealthOption {
+	return func(s *serviceHealth) {
+		s.caches = append(s.caches, caches...)
+	}
+}
+
+// WithHealthClock sets the clock the ping latency is measured with.
+func WithHealthClock(clock clock.Clock) HealthOption {
+	return func(s *serviceHealth) {
+		s.clock = clock
+	}
+}
+
+func NewHealthService(logger *logrus.Logger, healthRepository mysql.HealthRepository, configVersion string, options ...HealthOption) ServiceHealth {
+	s := &serviceHealth{
+		logger:           logger,
+		healthRepository: healthRepository,
+		configVersion:    configVersion,
+		clock:            clock.System,
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
+}
+
+// Check pings the database and reports the caches, config and build. It
+// reads nothing else, so warm-up pings leave usage and logs untouched.
+func (s *serviceHealth) Check(ctx context.Context) health.Report {
+	report := health.Report{
+		Status:        health.StatusOK,
+		Database:      health.Database{Status: health.StatusOK},
+		Cache:         map[string]int{},
+		ConfigVersion: s.configVersion,
+		Build:         buildinfo.Get(),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	start := s.clock.Now()
+	err := s.healthRepository.Ping(ctx)
+	report.Database.LatencyMs = float64(s.clock.Now().Sub(start).Microseconds()) / 1000
+	if err != nil {
+		report.Status = health.StatusDegraded
+		report.Database.Status = health.StatusDegraded
+		report.Database.Error = err.Error()
+	}
+
+	for _, cache := range s.caches {
+		for name, size := range cache.CacheStatus() {
+			report.Cache[name] = size
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"apikey/internal/model/health"
+	"apikey/internal/repository/memory"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// steppingClock moves forward by step every time it is read.
+type steppingClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (c *steppingClock) Now() time.Time {
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+type cacheStub map[string]int
+
+func (c cacheStub) CacheStatus() map[string]int {
+	return c
+}
+
+func TestHealthCheck(t *testing.T) {
+	repository := memory.NewHealthRepository()
+	clock := &steppingClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC), step: 1500 * time.Microsecond}
+	s := NewHealthService(newTestLogger(), repository, "v7",
+		WithHealthClock(clock),
+		WithCaches(cacheStub{"companies": 2}, cacheStub{"commerceRules": 1, "snapshot": 5}),
+	)
+
+	report := s.Check(context.Background())
+	if report.Status != health.StatusOK || report.Database.Status != health.StatusOK || report.Database.Error != "" {
+		t.Errorf("report = %+v, want ok", report)
+	}
+	if report.Database.LatencyMs != 1.5 {
+		t.Errorf("latency = %vms, want 1.5ms", report.Database.LatencyMs)
+	}
+	if report.ConfigVersion != "v7" {
+		t.Errorf("config version = %q", report.ConfigVersion)
+	}
+	want := map[string]int{"companies": 2, "commerceRules": 1, "snapshot": 5}
+	if len(report.Cache) != len(want) {
+		t.Errorf("cache = %v, want %v", report.Cache, want)
+	}
+	for name, size := range want {
+		if report.Cache[name] != size {
+			t.Errorf("cache %s = %d, want %d", name, report.Cache[name], size)
+		}
+	}
+}
+
+func TestHealthCheckDegraded(t *testing.T) {
+	repository := memory.NewHealthRepository()
+	s := NewHealthService(newTestLogger(), repository, "v7", WithCaches(cacheStub{"companies": 2}))
+	repository.Fail(errors.New("server selection timeout"))
+
+	report := s.Check(context.Background())
+	if report.Status != health.StatusDegraded || report.Database.Status != health.StatusDegraded {
+		t.Errorf("report = %+v, want degraded", report)
+	}
+	if report.Database.Error != "server selection timeout" {
+		t.Errorf("database error = %q", report.Database.Error)
+	}
+	if report.Cache["companies"] != 2 {
+		t.Errorf("cache = %v, a degraded report still lists the caches", report.Cache)
+	}
+
+	repository.Fail(nil)
+	if report := s.Check(context.Background()); report.Status != health.StatusOK {
+		t.Errorf("report after recovery = %+v, want ok", report)
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"apikey/internal/errormap"
+	"apikey/internal/model/apikey"
+	"apikey/internal/notify"
+	"apikey/pkg/metrics"
+	"context"
+	"fmt"
+	"time"
+)
+
+// notifyTimeout bounds the time an invocation waits on its alerts.
+const notifyTimeout = 2 * time.Second
+
+// checkQuota counts the call against each of the key's quota windows,
+// which reset at midnight of the key's timezone. Like checkRate a counter
+// failure lets the call through. The windows are kept on the key so their
+// bounds reach the authorizer context. When a window denies the call the
+// windows already counted are released, so a denied call uses no quota, and
+// the denying window joins them on the key.
+//
+// The plan policy of the key decides the soft thresholds that raise an
+// alert and how far above the limit calls are still allowed. Alerts are
+// sent in the background, the call that crossed the threshold is answered
+// without waiting on them.
+func (s *serviceApiKey) checkQuota(ctx context.Context, key *apikey.ApiKey) error {
+	limits := key.QuotaLimits()
+	if len(limits) == 0 {
+		return nil
+	}
+
+	now := s.clock.Now()
+	location := s.location(ctx, key)
+	policy := s.planPolicy(key)
+
+	for _, limit := range limits {
+		quota := apikey.Quota{
+			QuotaWindow: apikey.WindowAt(limit.Period, now, location),
+			Limit:       limit.Limit,
+		}
+		used, counted, err := s.counter.Increment(ctx, quotaCounterKey(key, quota), policy.HardLimit(limit.Limit), quota.End)
+		if err != nil {
+			s.logger.WithContext(ctx).Errorf("Error counting usage of apikey %s: %v", key.ClientID, err)
+			continue
+		}
+		quota.Used = used
+
+		if !counted {
+			s.logger.WithContext(ctx).Infof("Apikey %s exceeded its %s limit of %d", key.ClientID, limit.Period, limit.Limit)
+			s.metrics.Put(metrics.QuotaRejection, 1, metrics.Count, metrics.Dimensions{
+				metrics.ClientID: key.ClientID,
+				metrics.Plan:     key.UsageLimits.Plan,
+				metrics.Window:   string(limit.Period),
+			})
+			s.releaseQuota(ctx, key)
+			key.Quotas = append(key.Quotas, quota)
+			return &apikey.DenyError{
+				Err:     errormap.ErrQuotaExceeded,
+				Context: apikey.QuotaContext([]apikey.Quota{quota}),
+			}
+		}
+		key.Quotas = append(key.Quotas, quota)
+	}
+
+	for _, quota := range key.Quotas {
+		for _, threshold := range policy.Crossed(quota.Limit, quota.Used) {
+			s.alert(ctx, key, quota, threshold)
+		}
+	}
+
+	return nil
+}
+
+// releaseQuota gives back the call counted in the key's quota windows, the
+// windows stay on the key with the usage they had before the call.
+func (s *serviceApiKey) releaseQuota(ctx context.Context, key *apikey.ApiKey) {
+	for i, quota := range key.Quotas {
+		if err := s.counter.Decrement(ctx, quotaCounterKey(key, quota)); err != nil {
+			s.logger.WithContext(ctx).Errorf("Error releasing usage of apikey %s: %v", key.ClientID, err)
+			continue
+		}
+		key.Quotas[i].Used--
+	}
+}
+
+// planPolicy returns the policy of the key's plan, or the "default" one.
+func (s *serviceApiKey) planPolicy(key *apikey.ApiKey) apikey.PlanPolicy {
+	if policy, ok := s.planPolicies[key.UsageLimits.Plan]; ok {
+		return policy
+	}
+	return s.planPolicies["default"]
+}
+
+// alert notifies a crossed threshold, a failed notification is only logged.
+// Each threshold is notified once per window: the first call to claim it on
+// the counter sends it, so a usage released and counted again does not
+// repeat it. With the memory counter every container claims its own
+// thresholds, a shared counter backend is needed for a single alert.
+//
+// The claim and the notification run in the background, Flush waits for
+// them before Lambda freezes the environment.
+func (s *serviceApiKey) alert(ctx context.Context, key *apikey.ApiKey, quota apikey.Quota, threshold int) {
+	if s.notifier == nil {
+		return
+	}
+
+	claimKey := alertCounterKey(key, quota, threshold)
+	event := notify.Event{
+		Type:        notify.QuotaThreshold,
+		ClientID:    key.ClientID,
+		Plan:        key.UsageLimits.Plan,
+		Period:      string(quota.Period),
+		Threshold:   threshold,
+		Limit:       quota.Limit,
+		Used:        quota.Used,
+		WindowStart: quota.Start,
+		WindowEnd:   quota.End,
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+
+	s.alerts.Add(1)
+	go func() {
+		defer s.alerts.Done()
+		defer cancel()
+
+		_, claimed, err := s.counter.Increment(ctx, claimKey, 1, quota.End)
+		if err != nil {
+			s.logger.WithContext(ctx).Errorf("Error claiming %d%% threshold of apikey %s: %v", threshold, event.ClientID, err)
+			return
+		}
+		if !claimed {
+			return
+		}
+
+		if err := s.notifier.Notify(ctx, event); err != nil {
+			s.logger.WithContext(ctx).Errorf("Error notifying %d%% threshold of apikey %s: %v", threshold, event.ClientID, err)
+		}
+	}()
+}
+
+// Flush waits for the alerts sent in the background, or until ctx is done.
+func (s *serviceApiKey) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.alerts.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func quotaCounterKey(key *apikey.ApiKey, quota apikey.Quota) string {
+	return fmt.Sprintf("%s:%s:%d", quota.Period, key.ClientID, quota.Start.Unix())
+}
+
+func alertCounterKey(key *apikey.ApiKey, quota apikey.Quota, threshold int) string {
+	return fmt.Sprintf("alert:%d:%s", threshold, quotaCounterKey(key, quota))
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"apikey/internal/errormap"
+	"apikey/internal/model/apikey"
+	"apikey/internal/model/company"
+	"apikey/internal/model/gracePeriod"
+	"apikey/internal/notify"
+	"apikey/internal/ratelimit"
+	"apikey/internal/repository/memory"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQuotaDenyReleasesCountedWindows(t *testing.T) {
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	counter := ratelimit.NewMemoryCounter(clock)
+	s, repository := newTestService(clock, WithCounter(counter))
+	key := apikey.ApiKey{
+		ClientID:    "client-quota",
+		IsActive:    true,
+		ExpiredAt:   clock.now.Add(24 * time.Hour),
+		UsageLimits: apikey.UsageLimits{MonthlyLimit: 10, DailyLimit: 2},
+	}
+	repository.Put("quota", key)
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "quota"}); err != nil {
+			t.Fatalf("call %d within quota: %v", i, err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "quota"}); !errors.Is(err, errormap.ErrQuotaExceeded) {
+			t.Fatalf("call over the daily limit = %v, want %v", err, errormap.ErrQuotaExceeded)
+		}
+	}
+
+	monthly := apikey.Quota{QuotaWindow: apikey.WindowAt(apikey.MonthlyPeriod, clock.now, time.UTC)}
+	used, err := counter.Get(context.Background(), quotaCounterKey(&key, monthly))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used != 2 {
+		t.Errorf("monthly usage = %d, want 2, calls denied by the daily limit must not count", used)
+	}
+}
+
+func TestQuotaWindowsUseCompanyTimezone(t *testing.T) {
+	// 23:30 of September 7 in Santiago, where DST starts at midnight.
+	clock := &manualClock{now: time.Date(2024, 9, 8, 3, 30, 0, 0, time.UTC)}
+	companies := memory.NewCompanyRepository()
+	companies.Put(company.Company{ID: 7, Timezone: "America/Santiago"})
+	s, repository := newTestService(clock, WithCompanyRepository(companies))
+
+	limits := apikey.UsageLimits{DailyLimit: 1}
+	repository.Put("company", apikey.ApiKey{ClientID: "client-company", CompanyID: 7, IsActive: true, ExpiredAt: clock.now.Add(48 * time.Hour), UsageLimits: limits})
+	repository.Put("own", apikey.ApiKey{ClientID: "client-own", CompanyID: 7, Timezone: "UTC", IsActive: true, ExpiredAt: clock.now.Add(48 * time.Hour), UsageLimits: limits})
+
+	key, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "company"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 9, 8, 4, 0, 0, 0, time.UTC); !key.Quotas[0].End.Equal(want) {
+		t.Errorf("daily window ends at %v, want %v", key.Quotas[0].End.UTC(), want)
+	}
+	if _, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "own"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 00:30 of September 8 in UTC, 01:30 of September 8 in Santiago.
+	clock.now = clock.now.Add(time.Hour)
+	if _, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "company"}); err != nil {
+		t.Errorf("call on the next company day = %v, want allowed", err)
+	}
+	if _, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "own"}); !errors.Is(err, errormap.ErrQuotaExceeded) {
+		t.Errorf("call on the same day of the key timezone = %v, want %v", err, errormap.ErrQuotaExceeded)
+	}
+}
+
+// countingCounter counts the reads of the counters.
+type countingCounter struct {
+	ratelimit.Counter
+	reads int
+}
+
+func (c *countingCounter) Get(ctx context.Context, key string) (int64, error) {
+	c.reads++
+	return c.Counter.Get(ctx, key)
+}
+
+func TestDenyCarriesTheEvaluatedQuota(t *testing.T) {
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	counter := &countingCounter{Counter: ratelimit.NewMemoryCounter(clock)}
+	s, repository := newTestService(clock, WithCounter(counter))
+	limits := apikey.UsageLimits{DailyLimit: 1}
+	repository.Put("expired", apikey.ApiKey{ClientID: "client-expired", IsActive: true, ExpiredAt: clock.now.Add(-time.Hour), UsageLimits: limits})
+	repository.Put("quota", apikey.ApiKey{ClientID: "client-quota", IsActive: true, ExpiredAt: clock.now.Add(time.Hour), UsageLimits: limits})
+
+	if _, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "expired"}); !errors.Is(err, errormap.ErrKeyExpired) {
+		t.Fatalf("expired key = %v", err)
+	}
+	if counter.reads != 0 {
+		t.Errorf("an expired key read %d counters", counter.reads)
+	}
+
+	if _, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "quota"}); err != nil {
+		t.Fatal(err)
+	}
+	key, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "quota"})
+	if !errors.Is(err, errormap.ErrQuotaExceeded) {
+		t.Fatalf("call over quota = %v", err)
+	}
+	if len(key.Quotas) != 1 || key.Quotas[0].Used != 1 {
+		t.Errorf("quota deny carries %+v, want the daily quota used once", key.Quotas)
+	}
+	if counter.reads != 0 {
+		t.Errorf("a quota deny read %d counters, want the evaluated state", counter.reads)
+	}
+}
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []notify.Event
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, event notify.Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	return nil
+}
+
+func TestQuotaThresholdAlertsOncePerWindow(t *testing.T) {
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	notifier := &recordingNotifier{}
+	s, repository := newTestService(clock,
+		WithCounter(ratelimit.NewMemoryCounter(clock)),
+		WithNotifier(notifier),
+		WithPlanPolicies(map[string]apikey.PlanPolicy{"default": {Thresholds: []int{50, 100}}}),
+		WithGracePeriodRepository(rulesWithLimit("42", 2)),
+	)
+	repository.Put("alert", apikey.ApiKey{ClientID: "client-alert", CompanyID: 42, IsActive: true, ExpiredAt: clock.now.AddDate(1, 0, 0), UsageLimits: apikey.UsageLimits{DailyLimit: 6}})
+	call := apikey.Access{ApiKey: "alert", Route: "POST /payments", Payment: true}
+
+	// The third call reaches 50% and is denied by the transaction limit,
+	// its usage is released and the next call reaches 50% again.
+	for i := 0; i < 2; i++ {
+		if _, err := s.ValidateApiKey(context.Background(), call); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := s.ValidateApiKey(context.Background(), call); !errors.Is(err, errormap.ErrTransactionLimit) {
+			t.Fatalf("call over the transaction limit = %v", err)
+		}
+	}
+
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.events) != 1 || notifier.events[0].Threshold != 50 || notifier.events[0].Used != 3 {
+		t.Fatalf("events = %+v, want a single 50%% alert", notifier.events)
+	}
+
+	clock.now = clock.now.Add(24 * time.Hour)
+	for i := 0; i < 3; i++ {
+		s.ValidateApiKey(context.Background(), call)
+	}
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.events) != 2 {
+		t.Errorf("events = %+v, want the 50%% alert again in the next window", notifier.events)
+	}
+}
+
+func rulesWithLimit(commerceID string, limit int) *memory.GracePeriodRepository {
+	rules := memory.NewGracePeriodRepository()
+	rules.Put(gracePeriod.CommerceRule{IdCommerce: commerceID, LimitTransaction: limit})
+	return rules
+}
+
+type blockingNotifier struct {
+	release chan struct{}
+}
+
+func (n *blockingNotifier) Notify(ctx context.Context, event notify.Event) error {
+	<-n.release
+	return nil
+}
+
+func TestQuotaAlertDoesNotBlockTheCall(t *testing.T) {
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	notifier := &blockingNotifier{release: make(chan struct{})}
+	s, repository := newTestService(clock,
+		WithCounter(ratelimit.NewMemoryCounter(clock)),
+		WithNotifier(notifier),
+		WithPlanPolicies(map[string]apikey.PlanPolicy{"default": {Thresholds: []int{100}}}),
+	)
+	repository.Put("alert", apikey.ApiKey{ClientID: "client-alert", IsActive: true, ExpiredAt: clock.now.AddDate(1, 0, 0), UsageLimits: apikey.UsageLimits{DailyLimit: 1}})
+
+	if _, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "alert"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Flush(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("flush of a pending alert = %v, want %v", err, context.Canceled)
+	}
+	close(notifier.release)
+	if err := s.Flush(context.Background()); err != nil {
+		t.Errorf("flush = %v", err)
+	}
+}
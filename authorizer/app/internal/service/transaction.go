@@ -0,0 +1,148 @@
+package service
+
+import (
+	"apikey/internal/errormap"
+	"apikey/internal/model/apikey"
+	"apikey/internal/model/gracePeriod"
+	"apikey/pkg/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ruleTTL bounds how long commerce rules are reused between calls.
+const ruleTTL = 5 * time.Minute
+
+type cachedRule struct {
+	rule      *gracePeriod.CommerceRule
+	fetchedAt time.Time
+	expiresAt time.Time
+}
+
+type ruleCache struct {
+	mu    sync.Mutex
+	rules map[string]cachedRule
+}
+
+// checkTransaction applies the commerce rule of the key's company to calls
+// on payment routes: the amount may not exceed MaxAmount and at most
+// LimitTransaction transactions are allowed per day of the key's timezone.
+// Keys without a company and commerces without a rule are not restricted.
+func (s *serviceApiKey) checkTransaction(ctx context.Context, key *apikey.ApiKey, access apikey.Access) error {
+	if !access.Payment || s.gracePeriodRepository == nil {
+		return nil
+	}
+	commerceID := key.CommerceID()
+	if commerceID == "" {
+		return nil
+	}
+
+	rule, err := s.commerceRule(ctx, key, commerceID)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		return nil
+	}
+
+	if rule.MaxAmount > 0 {
+		amount, err := strconv.ParseFloat(access.Amount, 64)
+		if err != nil || amount < 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+			s.logger.WithContext(ctx).Infof("Apikey %s sent invalid amount %q", key.ClientID, access.Amount)
+			return errormap.ErrInvalidAmount
+		}
+		if amount > float64(rule.MaxAmount) {
+			s.logger.WithContext(ctx).Infof("Apikey %s amount %v over max amount %v", key.ClientID, amount, rule.MaxAmount)
+			return &apikey.DenyError{
+				Err:     errormap.ErrAmountExceeded,
+				Context: map[string]interface{}{"maxAmount": rule.MaxAmount},
+			}
+		}
+	}
+
+	if rule.LimitTransaction > 0 {
+		window := apikey.WindowAt(apikey.DailyPeriod, s.clock.Now(), s.location(ctx, key))
+		counterKey := fmt.Sprintf("transactions:%s:%d", commerceID, window.Start.Unix())
+
+		_, counted, err := s.counter.Increment(ctx, counterKey, int64(rule.LimitTransaction), window.End)
+		if err != nil {
+			s.logger.WithContext(ctx).Errorf("Error counting transactions of apikey %s: %v", key.ClientID, err)
+			return nil
+		}
+		if !counted {
+			s.logger.WithContext(ctx).Infof("Apikey %s exceeded its limit of %d transactions", key.ClientID, rule.LimitTransaction)
+			return &apikey.DenyError{
+				Err: errormap.ErrTransactionLimit,
+				Context: map[string]interface{}{
+					"transactionLimit":   rule.LimitTransaction,
+					"transactionResetAt": window.End.Format(time.RFC3339),
+				},
+			}
+		}
+	}
+
+	return nil
+}
+
+// commerceRule returns the rule of the commerce, nil when it has none.
+// Rules, and their absence, are reused for ruleTTL. A failed lookup is
+// answered by the fail policy like a failed key lookup.
+func (s *serviceApiKey) commerceRule(ctx context.Context, key *apikey.ApiKey, commerceID string) (*gracePeriod.CommerceRule, error) {
+	now := s.clock.Now()
+	s.rules.mu.Lock()
+	cached, ok := s.rules.rules[commerceID]
+	s.rules.mu.Unlock()
+	hit := ok && now.Before(cached.expiresAt)
+	s.metrics.Put(metrics.CacheHit, hitValue(hit), metrics.None, metrics.Dimensions{metrics.Cache: "commerce_rule"})
+	if hit {
+		return cached.rule, nil
+	}
+
+	rule, err := s.gracePeriodRepository.ValidationGracePeriod(ctx, commerceID)
+	if errors.Is(err, errormap.ErrNoRows) {
+		rule = nil
+	} else if err != nil {
+		s.logger.WithContext(ctx).Errorf("Error getting commerce rule %s of apikey %s: %v", commerceID, key.ClientID, err)
+		return s.ruleFallback(ctx, key, commerceID, cached, ok, err)
+	}
+
+	s.rules.mu.Lock()
+	s.rules.rules[commerceID] = cachedRule{rule: rule, fetchedAt: now, expiresAt: now.Add(ruleTTL)}
+	s.rules.mu.Unlock()
+
+	return rule, nil
+}
+
+// ruleFallback answers a rule lookup that failed with err according to the
+// fail policy: closed fails the call, static reuses the last rule read while
+// it is not older than MaxStaleness, and open also reuses a stale rule, or
+// lets the call through without one, for the OpenClients.
+func (s *serviceApiKey) ruleFallback(ctx context.Context, key *apikey.ApiKey, commerceID string, cached cachedRule, found bool, err error) (*gracePeriod.CommerceRule, error) {
+	staleness := s.clock.Now().Sub(cached.fetchedAt)
+	fresh := found && staleness <= s.failPolicy.MaxStaleness
+
+	switch s.failPolicy.Mode {
+	case FailStatic:
+		if !fresh {
+			return nil, err
+		}
+	case FailOpen:
+		if !fresh && !s.openClient(key.ClientID) {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if !found {
+		s.logger.WithContext(ctx).Warnf("Fail %s: no commerce rule %s for apikey %s after: %v", s.failPolicy.Mode, commerceID, key.ClientID, err)
+		return nil, nil
+	}
+	s.logger.WithContext(ctx).Warnf("Fail %s: reusing commerce rule %s for apikey %s (staleness %v) after: %v",
+		s.failPolicy.Mode, commerceID, key.ClientID, staleness, err)
+	return cached.rule, nil
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"apikey/internal/errormap"
+	"apikey/internal/model/apikey"
+	"apikey/internal/model/gracePeriod"
+	"apikey/internal/ratelimit"
+	"apikey/internal/repository/memory"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTransactionService(t *testing.T, clock *manualClock, rule gracePeriod.CommerceRule, limits apikey.UsageLimits) (ServiceApiKey, *memory.GracePeriodRepository, ratelimit.Counter) {
+	t.Helper()
+	rules := memory.NewGracePeriodRepository()
+	rules.Put(rule)
+	counter := ratelimit.NewMemoryCounter(clock)
+	s, repository := newTestService(clock, WithGracePeriodRepository(rules), WithCounter(counter))
+	repository.Put("payments", apikey.ApiKey{ClientID: "client-payments", CompanyID: 42, IsActive: true, ExpiredAt: clock.now.AddDate(1, 0, 0), UsageLimits: limits})
+	return s, rules, counter
+}
+
+func payment(amount string) apikey.Access {
+	return apikey.Access{ApiKey: "payments", Route: "POST /payments", Payment: true, Amount: amount}
+}
+
+func TestTransactionMaxAmount(t *testing.T) {
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	s, _, _ := newTransactionService(t, clock, gracePeriod.CommerceRule{IdCommerce: "42", MaxAmount: 1000}, apikey.UsageLimits{})
+
+	tests := []struct {
+		amount string
+		want   error
+	}{
+		{"999.99", nil},
+		{"1000", nil},
+		{"0", nil},
+		{"1000.01", errormap.ErrAmountExceeded},
+		{"", errormap.ErrInvalidAmount},
+		{"abc", errormap.ErrInvalidAmount},
+		{"-1", errormap.ErrInvalidAmount},
+		{"NaN", errormap.ErrInvalidAmount},
+		{"nan", errormap.ErrInvalidAmount},
+		{"Inf", errormap.ErrInvalidAmount},
+		{"-Inf", errormap.ErrInvalidAmount},
+		{"1e400", errormap.ErrInvalidAmount},
+	}
+	for _, test := range tests {
+		_, err := s.ValidateApiKey(context.Background(), payment(test.amount))
+		if !errors.Is(err, test.want) || (test.want == nil && err != nil) {
+			t.Errorf("amount %q = %v, want %v", test.amount, err, test.want)
+		}
+	}
+
+	var deny *apikey.DenyError
+	_, err := s.ValidateApiKey(context.Background(), payment("5000"))
+	if !errors.As(err, &deny) || deny.Context["maxAmount"] != float32(1000) {
+		t.Errorf("deny context = %v, want the max amount", err)
+	}
+
+	if _, err := s.ValidateApiKey(context.Background(), apikey.Access{ApiKey: "payments", Route: "GET /orders"}); err != nil {
+		t.Errorf("call outside payment routes = %v, want allowed", err)
+	}
+}
+
+func TestTransactionLimit(t *testing.T) {
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	s, _, _ := newTransactionService(t, clock, gracePeriod.CommerceRule{IdCommerce: "42", LimitTransaction: 2}, apikey.UsageLimits{})
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.ValidateApiKey(context.Background(), payment("")); err != nil {
+			t.Fatalf("transaction %d = %v", i, err)
+		}
+	}
+	_, err := s.ValidateApiKey(context.Background(), payment(""))
+	var deny *apikey.DenyError
+	if !errors.As(err, &deny) || !errors.Is(err, errormap.ErrTransactionLimit) {
+		t.Fatalf("transaction over the limit = %v, want %v", err, errormap.ErrTransactionLimit)
+	}
+	if deny.Context["transactionResetAt"] != "2026-10-19T00:00:00Z" {
+		t.Errorf("deny context = %v", deny.Context)
+	}
+
+	clock.now = clock.now.Add(12 * time.Hour)
+	if _, err := s.ValidateApiKey(context.Background(), payment("")); err != nil {
+		t.Errorf("transaction on the next day = %v, want allowed", err)
+	}
+}
+
+func TestTransactionDenyReleasesQuota(t *testing.T) {
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	rule := gracePeriod.CommerceRule{IdCommerce: "42", LimitTransaction: 1}
+	s, _, counter := newTransactionService(t, clock, rule, apikey.UsageLimits{DailyLimit: 10})
+
+	if _, err := s.ValidateApiKey(context.Background(), payment("")); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		key, err := s.ValidateApiKey(context.Background(), payment(""))
+		if !errors.Is(err, errormap.ErrTransactionLimit) {
+			t.Fatalf("transaction over the limit = %v", err)
+		}
+		if state := key.QuotaState(); state.Limit != 10 || state.Remaining != 9 {
+			t.Errorf("transaction deny quota = %+v, want 9 of 10 remaining", state)
+		}
+	}
+
+	daily := apikey.Quota{QuotaWindow: apikey.WindowAt(apikey.DailyPeriod, clock.now, time.UTC)}
+	used, err := counter.Get(context.Background(), quotaCounterKey(&apikey.ApiKey{ClientID: "client-payments"}, daily))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used != 1 {
+		t.Errorf("daily usage = %d, want 1, calls denied by the transaction limit must not count", used)
+	}
+}
+
+func TestTransactionRuleIsCached(t *testing.T) {
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	s, rules, _ := newTransactionService(t, clock, gracePeriod.CommerceRule{IdCommerce: "42", MaxAmount: 100}, apikey.UsageLimits{})
+
+	if _, err := s.ValidateApiKey(context.Background(), payment("50")); err != nil {
+		t.Fatal(err)
+	}
+
+	down := errors.New("connection refused")
+	rules.Fail(down)
+	if _, err := s.ValidateApiKey(context.Background(), payment("500")); !errors.Is(err, errormap.ErrAmountExceeded) {
+		t.Errorf("cached rule = %v, want %v", err, errormap.ErrAmountExceeded)
+	}
+
+	clock.now = clock.now.Add(ruleTTL)
+	if _, err := s.ValidateApiKey(context.Background(), payment("50")); !errors.Is(err, down) {
+		t.Errorf("expired rule = %v, want the database error", err)
+	}
+}
+
+func TestTransactionRuleFailPolicy(t *testing.T) {
+	down := errors.New("connection refused")
+	rule := gracePeriod.CommerceRule{IdCommerce: "42", MaxAmount: 100}
+	for _, tc := range []struct {
+		name   string
+		policy FailPolicy
+		cached bool
+		want   error
+	}{
+		{name: "closed", policy: FailPolicy{Mode: FailClosed, MaxStaleness: time.Hour}, cached: true, want: down},
+		{name: "static with a fresh rule", policy: FailPolicy{Mode: FailStatic, MaxStaleness: time.Hour}, cached: true, want: errormap.ErrAmountExceeded},
+		{name: "static with a stale rule", policy: FailPolicy{Mode: FailStatic, MaxStaleness: time.Minute}, cached: true, want: down},
+		{name: "static without a rule", policy: FailPolicy{Mode: FailStatic, MaxStaleness: time.Hour}, want: down},
+		{name: "open with a stale rule", policy: FailPolicy{Mode: FailOpen, MaxStaleness: time.Minute, OpenClients: []string{"client-payments"}}, cached: true, want: errormap.ErrAmountExceeded},
+		{name: "open without a rule", policy: FailPolicy{Mode: FailOpen, MaxStaleness: time.Minute, OpenClients: []string{"client-payments"}}},
+		{name: "open for another client", policy: FailPolicy{Mode: FailOpen, MaxStaleness: time.Minute, OpenClients: []string{"client-other"}}, want: down},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+			rules := memory.NewGracePeriodRepository()
+			rules.Put(rule)
+			s, repository := newTestService(clock, WithGracePeriodRepository(rules), WithCounter(ratelimit.NewMemoryCounter(clock)), WithFailPolicy(tc.policy, nil))
+			repository.Put("payments", apikey.ApiKey{ClientID: "client-payments", CompanyID: 42, IsActive: true, ExpiredAt: clock.now.AddDate(1, 0, 0)})
+
+			if tc.cached {
+				if _, err := s.ValidateApiKey(context.Background(), payment("50")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			rules.Fail(down)
+			clock.now = clock.now.Add(ruleTTL)
+
+			if _, err := s.ValidateApiKey(context.Background(), payment("500")); !errors.Is(err, tc.want) {
+				t.Errorf("payment while the rules are down = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestTransactionWithoutCompany(t *testing.T) {
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	s, rules, _ := newTransactionService(t, clock, gracePeriod.CommerceRule{IdCommerce: "42", MaxAmount: 1}, apikey.UsageLimits{})
+	rules.Fail(errors.New("connection refused"))
+	s.(*serviceApiKey).apikeyRepository.(*memory.ApiKeyRepository).Put("payments", apikey.ApiKey{ClientID: "client-payments", IsActive: true, ExpiredAt: clock.now.Add(time.Hour)})
+
+	if _, err := s.ValidateApiKey(context.Background(), payment("abc")); err != nil {
+		t.Errorf("key without company = %v, want allowed", err)
+	}
+}
+
+func TestTransactionWithoutRule(t *testing.T) {
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	s, _, _ := newTransactionService(t, clock, gracePeriod.CommerceRule{IdCommerce: "43", MaxAmount: 1}, apikey.UsageLimits{})
+
+	if _, err := s.ValidateApiKey(context.Background(), payment("abc")); err != nil {
+		t.Errorf("commerce without rule = %v, want allowed", err)
+	}
+}
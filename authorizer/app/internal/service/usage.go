@@ -0,0 +1,91 @@
+package service
+
+import (
+	"apikey/internal/errormap"
+	"apikey/internal/model/usage"
+	mysql "apikey/internal/repository"
+	"apikey/pkg/errorx"
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxUsageRange   = 366
+	defaultTopLimit = 10
+	maxTopLimit     = 100
+)
+
+type ServiceUsage interface {
+	Series(ctx context.Context, clientID, from, to string) (usage.Series, error)
+	TopConsumers(ctx context.Context, from, to string, limit int) (usage.Consumers, error)
+}
+
+type serviceUsage struct {
+	logger          *logrus.Logger
+	usageRepository mysql.UsageRepository
+}
+
+func NewUsageService(logger *logrus.Logger, usageRepository mysql.UsageRepository) ServiceUsage {
+	return &serviceUsage{
+		logger:          logger,
+		usageRepository: usageRepository,
+	}
+}
+
+var ErrInvalidDateRange = errorx.NewErrorf(errormap.CodeInvalidArgument, "invalid date range, from and to must be YYYY-MM-DD, from not after to and at most %d days apart", maxUsageRange)
+
+func (s *serviceUsage) Series(ctx context.Context, clientID, from, to string) (usage.Series, error) {
+	if clientID == "" {
+		return nil, ErrInvalidArgument
+	}
+	if err := validateRange(from, to); err != nil {
+		return nil, err
+	}
+
+	series, err := s.usageRepository.Series(ctx, clientID, from, to)
+	if err != nil {
+		s.logger.WithContext(ctx).Errorf("Error getting usage of %s: %v", clientID, err)
+		return nil, err
+	}
+
+	return series, nil
+}
+
+func (s *serviceUsage) TopConsumers(ctx context.Context, from, to string, limit int) (usage.Consumers, error) {
+	if err := validateRange(from, to); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultTopLimit
+	}
+	if limit > maxTopLimit {
+		limit = maxTopLimit
+	}
+
+	consumers, err := s.usageRepository.TopConsumers(ctx, from, to, limit)
+	if err != nil {
+		s.logger.WithContext(ctx).Errorf("Error getting top consumers: %v", err)
+		return nil, err
+	}
+
+	return consumers, nil
+}
+
+func validateRange(from, to string) error {
+	invalid := ErrInvalidDateRange.WithField("from", from).WithField("to", to)
+
+	start, err := time.Parse(usage.DayLayout, from)
+	if err != nil {
+		return invalid.WithOrigin(err)
+	}
+	end, err := time.Parse(usage.DayLayout, to)
+	if err != nil {
+		return invalid.WithOrigin(err)
+	}
+	if end.Before(start) || end.Sub(start) > maxUsageRange*24*time.Hour {
+		return invalid
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"apikey/internal/model/apikey"
+	"apikey/internal/repository/memory"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateRange(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		ok   bool
+	}{
+		{"2026-10-01", "2026-10-18", true},
+		{"2026-10-18", "2026-10-18", true},
+		{"2025-10-17", "2026-10-18", true},
+		{"2025-10-16", "2026-10-18", false},
+		{"2026-10-18", "2026-10-01", false},
+		{"2026/10/01", "2026-10-18", false},
+		{"2026-10-01", "", false},
+		{"", "", false},
+	}
+	for _, test := range tests {
+		err := validateRange(test.from, test.to)
+		if (err == nil) != test.ok {
+			t.Errorf("validateRange(%q, %q) = %v, want ok %v", test.from, test.to, err, test.ok)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidDateRange) {
+			t.Errorf("validateRange(%q, %q) = %v, want %v", test.from, test.to, err, ErrInvalidDateRange)
+		}
+	}
+}
+
+func TestSeries(t *testing.T) {
+	ctx := context.Background()
+	repository := memory.NewUsageRepository()
+	for _, record := range []struct{ clientID, day, route string }{
+		{"client-1", "2026-10-17", "GET /orders/{id}"},
+		{"client-1", "2026-10-17", "GET /orders/{id}"},
+		{"client-1", "2026-10-17", "POST /payments"},
+		{"client-1", "2026-10-18", "GET /orders/{id}"},
+		{"client-1", "2026-09-30", "GET /orders/{id}"},
+		{"client-2", "2026-10-17", "GET /orders/{id}"},
+	} {
+		if err := repository.Record(ctx, record.clientID, record.day, record.route, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewUsageService(newTestLogger(), repository)
+
+	series, err := s.Series(ctx, "client-1", "2026-10-01", "2026-10-18")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 2 || series[0].Day != "2026-10-17" || series[1].Day != "2026-10-18" {
+		t.Fatalf("series = %+v", series)
+	}
+	if series[0].Count != 3 || series[0].Routes["GET /orders/{id}"] != 2 || series[0].Routes["POST /payments"] != 1 {
+		t.Errorf("2026-10-17 = %+v", series[0])
+	}
+
+	if _, err := s.Series(ctx, "", "2026-10-01", "2026-10-18"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Series without client = %v, want %v", err, ErrInvalidArgument)
+	}
+	if _, err := s.Series(ctx, "client-1", "2026-10-18", "2026-10-01"); !errors.Is(err, ErrInvalidDateRange) {
+		t.Errorf("Series with reversed range = %v, want %v", err, ErrInvalidDateRange)
+	}
+
+	down := errors.New("connection refused")
+	repository.Fail(down)
+	if _, err := s.Series(ctx, "client-1", "2026-10-01", "2026-10-18"); !errors.Is(err, down) {
+		t.Errorf("Series with the database down = %v, want %v", err, down)
+	}
+}
+
+func TestRecordUsageWithoutRouteTemplate(t *testing.T) {
+	ctx := context.Background()
+	clock := &manualClock{now: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	usage := memory.NewUsageRepository()
+	s, _ := newTestService(clock, WithUsageRepository(usage))
+
+	accesses := []apikey.Access{
+		{ApiKey: "active", Method: "GET", Route: "GET /orders/{id}", RouteTemplate: true},
+		{ApiKey: "active", Method: "GET", Route: "GET /orders/123"},
+		{ApiKey: "active", Method: "GET", Route: "GET /orders/456"},
+		{ApiKey: "active", Method: "POST", Route: "POST /orders"},
+	}
+	for _, access := range accesses {
+		if _, err := s.ValidateApiKey(ctx, access); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	series, err := usage.Series(ctx, "client-1", "2026-10-18", "2026-10-18")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{"GET /orders/{id}": 1, "GET *": 2, "POST *": 1}
+	if len(series) != 1 || series[0].Count != 4 || !reflect.DeepEqual(series[0].Routes, want) {
+		t.Errorf("series = %+v, want every call counted and untemplated ones under %v", series, want)
+	}
+}
+
+func TestRecordUsageDayFollowsTheClock(t *testing.T) {
+	ctx := context.Background()
+	// 23:30 of September 7 in Santiago, where DST starts at midnight.
+	clock := &manualClock{now: time.Date(2024, 9, 8, 3, 30, 0, 0, time.UTC)}
+	usage := memory.NewUsageRepository()
+	s, repository := newTestService(clock, WithUsageRepository(usage))
+	repository.Put("santiago", apikey.ApiKey{ClientID: "client-santiago", Timezone: "America/Santiago", IsActive: true, ExpiredAt: clock.now.AddDate(1, 0, 0)})
+
+	access := apikey.Access{ApiKey: "santiago", Method: "GET", Route: "GET /orders", RouteTemplate: true}
+	for _, step := range []time.Duration{0, 20 * time.Minute, time.Hour} {
+		clock.now = clock.now.Add(step)
+		if _, err := s.ValidateApiKey(ctx, access); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	series, err := usage.Series(ctx, "client-santiago", "2024-09-07", "2024-09-08")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 2 || series[0].Day != "2024-09-07" || series[0].Count != 2 || series[1].Day != "2024-09-08" || series[1].Count != 1 {
+		t.Errorf("series = %+v, want 2 calls on September 7 and 1 on September 8", series)
+	}
+}
@@ -0,0 +1,136 @@
+package snapshot
+
+import (
+	"apikey/internal/model/apikey"
+	"apikey/pkg/clock"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// persistInterval bounds how often the snapshot is written to disk.
+const persistInterval = 30 * time.Second
+
+// Entry is the last known good version of a key.
+type Entry struct {
+	Key    apikey.ApiKey `json:"key"`
+	SeenAt time.Time     `json:"seenAt"`
+}
+
+// Store keeps the active keys read from the database, indexed by their
+// fingerprint, so decisions can be served while the database is down. It
+// lives in memory and is persisted to a local file that is loaded back on
+// start, the file never holds the keys themselves.
+type Store struct {
+	mu          sync.Mutex
+	logger      *logrus.Logger
+	clock       clock.Clock
+	path        string
+	entries     map[string]Entry
+	persistedAt time.Time
+}
+
+func New(logger *logrus.Logger, clock clock.Clock, path string) *Store {
+	s := &Store{
+		logger:  logger,
+		clock:   clock,
+		path:    path,
+		entries: map[string]Entry{},
+	}
+	s.load()
+	return s
+}
+
+// Put records key as last seen now, inactive keys are dropped.
+func (s *Store) Put(apiKey string, key *apikey.ApiKey) {
+	fingerprint := apikey.Fingerprint(apiKey)
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !key.IsActive {
+		delete(s.entries, fingerprint)
+		return
+	}
+	s.entries[fingerprint] = Entry{Key: *key, SeenAt: now}
+
+	if now.Sub(s.persistedAt) >= persistInterval {
+		s.persist()
+		s.persistedAt = now
+	}
+}
+
+// Get returns a copy of the last known good key and how old it is.
+func (s *Store) Get(apiKey string) (*apikey.ApiKey, time.Duration, bool) {
+	s.mu.Lock()
+	entry, ok := s.entries[apikey.Fingerprint(apiKey)]
+	s.mu.Unlock()
+	if !ok {
+		return nil, 0, false
+	}
+
+	key := entry.Key
+	return &key, s.clock.Now().Sub(entry.SeenAt), true
+}
+
+// Len returns the number of keys in the snapshot.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+func (s *Store) load() {
+	if s.path == "" {
+		return
+	}
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		s.logger.Errorf("error reading snapshot %s : %v", s.path, err)
+		return
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		s.logger.Errorf("error decoding snapshot %s : %v", s.path, err)
+		s.entries = map[string]Entry{}
+	}
+}
+
+// persist writes the snapshot through a temporary file so a reader never
+// sees it half written. Callers hold the lock.
+func (s *Store) persist() {
+	if s.path == "" {
+		return
+	}
+	data, err := json.Marshal(s.entries)
+	if err != nil {
+		s.logger.Errorf("error encoding snapshot : %v", err)
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		s.logger.Errorf("error writing snapshot %s : %v", s.path, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		s.logger.Errorf("error writing snapshot %s : %v", s.path, err)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		s.logger.Errorf("error writing snapshot %s : %v", s.path, err)
+		return
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		s.logger.Errorf("error writing snapshot %s : %v", s.path, err)
+	}
+}
@@ -0,0 +1,37 @@
+package buildinfo
+
+import "runtime/debug"
+
+// Version is set at build time with
+// -ldflags "-X apikey/pkg/buildinfo.Version=<version>".
+var Version = "dev"
+
+type Info struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the version and the VCS information Go stamped in the binary.
+func Get() Info {
+	info := Info{Version: Version}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.GoVersion = build.GoVersion
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Revision = setting.Value
+		case "vcs.time":
+			info.Time = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
@@ -0,0 +1,25 @@
+package clock
+
+import "time"
+
+// Clock tells the current time, it is injected wherever time based rules
+// are evaluated so they can be exercised at any instant.
+type Clock interface {
+	Now() time.Time
+}
+
+// Func adapts a function to the Clock interface.
+type Func func() time.Time
+
+// Now calls f.
+func (f Func) Now() time.Time {
+	return f()
+}
+
+// System is the wall clock.
+var System Clock = Func(time.Now)
+
+// Fixed returns a clock that always tells t.
+func Fixed(t time.Time) Clock {
+	return Func(func() time.Time { return t })
+}
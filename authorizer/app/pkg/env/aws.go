@@ -0,0 +1,102 @@
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// SecretsManagerAPI is the part of the Secrets Manager client used here.
+type SecretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// SSMAPI is the part of the SSM client used here.
+type SSMAPI interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// SecretsManagerProvider resolves secret ids, optionally followed by
+// #field to read one key of a JSON secret.
+func SecretsManagerProvider(client SecretsManagerAPI) Provider {
+	return ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		id, field := jsonField(ref)
+		out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(id),
+		})
+		if err != nil {
+			return "", errProvider(SchemeSecretsManager, ref, err)
+		}
+
+		value := aws.ToString(out.SecretString)
+		if field == "" {
+			return value, nil
+		}
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &fields); err != nil {
+			return "", errProvider(SchemeSecretsManager, ref, err)
+		}
+		v, ok := fields[field]
+		if !ok {
+			return "", errProvider(SchemeSecretsManager, ref, fmt.Errorf("field %s not found", field))
+		}
+		return fmt.Sprint(v), nil
+	})
+}
+
+// SSMProvider resolves parameter names, decrypting SecureString values.
+func SSMProvider(client SSMAPI) Provider {
+	return ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		out, err := client.GetParameter(ctx, &ssm.GetParameterInput{
+			Name:           aws.String(ref),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return "", errProvider(SchemeSSM, ref, err)
+		}
+		return aws.ToString(out.Parameter.Value), nil
+	})
+}
+
+// awsClients loads the AWS configuration on first use so deployments
+// without secret references never touch it.
+type awsClients struct {
+	once           sync.Once
+	err            error
+	secretsManager *secretsmanager.Client
+	ssm            *ssm.Client
+}
+
+func (c *awsClients) load(ctx context.Context) error {
+	c.once.Do(func() {
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			c.err = err
+			return
+		}
+		c.secretsManager = secretsmanager.NewFromConfig(cfg)
+		c.ssm = ssm.NewFromConfig(cfg)
+	})
+	return c.err
+}
+
+func (c *awsClients) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	if err := c.load(ctx); err != nil {
+		return nil, err
+	}
+	return c.secretsManager.GetSecretValue(ctx, params, optFns...)
+}
+
+func (c *awsClients) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	if err := c.load(ctx); err != nil {
+		return nil, err
+	}
+	return c.ssm.GetParameter(ctx, params, optFns...)
+}
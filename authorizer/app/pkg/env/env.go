@@ -3,6 +3,7 @@ package env
 import (
 	"fmt"
 	"os"
+	"strings"
 )
 
 func Validate(variables []string) error {
@@ -15,10 +16,17 @@ func Validate(variables []string) error {
 	return nil
 }
 
+// GetEnvs lists the mandatory variables, GRAYLOG_ADDR only when logs are
+// sent to Graylog.
 func GetEnvs() []string {
-	return []string{
+	envs := []string{
 		"USER_VAR_LOG_CHAN",
 		"USER_VAR_LOG_LEVEL",
 		"USER_VAR_DB_MONGO_URI",
 	}
+	switch strings.ToLower(os.Getenv("USER_VAR_LOG_CHAN")) {
+	case "stdgraylog", "stdgrayout":
+		envs = append(envs, "GRAYLOG_ADDR")
+	}
+	return envs
 }
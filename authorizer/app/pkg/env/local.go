@@ -0,0 +1,73 @@
+package env
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// LocalStore stands in for Secrets Manager and SSM in tests and local runs.
+// It reads a JSON file on every call so edits are picked up on refresh:
+//
+//	{
+//	  "secretsmanager": {"authorizer/documentdb": "{\"uri\":\"mongodb://...\"}"},
+//	  "ssm": {"/authorizer/log-level": "DEBUG"}
+//	}
+type LocalStore struct {
+	Path string
+}
+
+type localSecrets struct {
+	SecretsManager map[string]string `json:"secretsmanager"`
+	SSM            map[string]string `json:"ssm"`
+}
+
+func (s *LocalStore) read() (*localSecrets, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	var secrets localSecrets
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("invalid local secrets file %s: %w", s.Path, err)
+	}
+	return &secrets, nil
+}
+
+func (s *LocalStore) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	secrets, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	value, ok := secrets.SecretsManager[aws.ToString(params.SecretId)]
+	if !ok {
+		return nil, fmt.Errorf("secret %s not found", aws.ToString(params.SecretId))
+	}
+	return &secretsmanager.GetSecretValueOutput{
+		Name:         params.SecretId,
+		SecretString: aws.String(value),
+	}, nil
+}
+
+func (s *LocalStore) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	secrets, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	value, ok := secrets.SSM[aws.ToString(params.Name)]
+	if !ok {
+		return nil, fmt.Errorf("parameter %s not found", aws.ToString(params.Name))
+	}
+	return &ssm.GetParameterOutput{
+		Parameter: &ssmtypes.Parameter{
+			Name:  params.Name,
+			Value: aws.String(value),
+		},
+	}, nil
+}
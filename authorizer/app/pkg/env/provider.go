@@ -0,0 +1,64 @@
+package env
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	SchemeFile           = "file"
+	SchemeSecretsManager = "secretsmanager"
+	SchemeSSM            = "ssm"
+)
+
+// Provider resolves the reference part of a configuration value,
+// e.g. the secret id of secretsmanager:authorizer/documentdb.
+type Provider interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// ProviderFunc adapts a function to the Provider interface.
+type ProviderFunc func(ctx context.Context, ref string) (string, error)
+
+// Resolve calls f.
+func (f ProviderFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// FileProvider reads the value from the file at ref, as mounted secrets are.
+func FileProvider() Provider {
+	return ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		data, err := os.ReadFile(ref)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	})
+}
+
+// splitReference splits scheme:ref values, values whose scheme has no
+// provider are plain values.
+func splitReference(value string, providers map[string]Provider) (Provider, string, bool) {
+	scheme, ref, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, "", false
+	}
+	provider, ok := providers[scheme]
+	if !ok {
+		return nil, "", false
+	}
+	return provider, ref, true
+}
+
+// jsonField splits the optional #field suffix used to pick a key out of a
+// JSON secret, secretsmanager:authorizer/documentdb#uri.
+func jsonField(ref string) (string, string) {
+	id, field, _ := strings.Cut(ref, "#")
+	return id, field
+}
+
+func errProvider(scheme, ref string, err error) error {
+	return fmt.Errorf("resolving %s:%s: %w", scheme, ref, err)
+}
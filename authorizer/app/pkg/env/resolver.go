@@ -0,0 +1,145 @@
+package env
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultTTL = 5 * time.Minute
+
+// Resolver reads environment variables whose value is either plain or a
+// scheme:ref reference resolved by a Provider (file:/run/secrets/uri,
+// secretsmanager:authorizer/documentdb#uri, ssm:/authorizer/uri).
+// Resolved values are cached for the TTL, when a refresh fails the last
+// value keeps being served. Connections resolve their settings once, on cold
+// start, so rotated credentials are picked up by new containers.
+type Resolver struct {
+	mu        sync.Mutex
+	providers map[string]Provider
+	ttl       time.Duration
+	now       func() time.Time
+	cache     map[string]cachedValue
+}
+
+type cachedValue struct {
+	value     string
+	expiresAt time.Time
+}
+
+type ResolverOption func(*Resolver)
+
+func WithProvider(scheme string, provider Provider) ResolverOption {
+	return func(r *Resolver) {
+		r.providers[scheme] = provider
+	}
+}
+
+func WithTTL(ttl time.Duration) ResolverOption {
+	return func(r *Resolver) {
+		r.ttl = ttl
+	}
+}
+
+func WithNow(now func() time.Time) ResolverOption {
+	return func(r *Resolver) {
+		r.now = now
+	}
+}
+
+func NewResolver(options ...ResolverOption) *Resolver {
+	r := &Resolver{
+		providers: map[string]Provider{},
+		ttl:       defaultTTL,
+		now:       time.Now,
+		cache:     map[string]cachedValue{},
+	}
+
+	for _, option := range options {
+		option(r)
+	}
+
+	return r
+}
+
+// Get returns the resolved value of the environment variable name.
+func (r *Resolver) Get(ctx context.Context, name string) (string, error) {
+	value := os.Getenv(name)
+	provider, ref, ok := splitReference(value, r.providers)
+	if !ok {
+		return value, nil
+	}
+
+	r.mu.Lock()
+	cached, found := r.cache[value]
+	r.mu.Unlock()
+	if found && r.now().Before(cached.expiresAt) {
+		return cached.value, nil
+	}
+
+	resolved, err := provider.Resolve(ctx, ref)
+	if err != nil {
+		if found {
+			return cached.value, nil
+		}
+		return "", err
+	}
+
+	r.mu.Lock()
+	r.cache[value] = cachedValue{value: resolved, expiresAt: r.now().Add(r.ttl)}
+	r.mu.Unlock()
+
+	return resolved, nil
+}
+
+// NewDefaultResolver resolves file, Secrets Manager and SSM references.
+// When USER_VAR_SECRETS_LOCAL_FILE is set Secrets Manager and SSM are read
+// from that file instead of AWS. USER_VAR_SECRETS_TTL sets the cache TTL.
+func NewDefaultResolver() *Resolver {
+	var (
+		localFile = os.Getenv("USER_VAR_SECRETS_LOCAL_FILE")
+		ttl       = os.Getenv("USER_VAR_SECRETS_TTL")
+	)
+
+	var (
+		secretsManager SecretsManagerAPI
+		parameters     SSMAPI
+	)
+	if localFile != "" {
+		store := &LocalStore{Path: localFile}
+		secretsManager, parameters = store, store
+	} else {
+		clients := &awsClients{}
+		secretsManager, parameters = clients, clients
+	}
+
+	options := []ResolverOption{
+		WithProvider(SchemeFile, FileProvider()),
+		WithProvider(SchemeSecretsManager, SecretsManagerProvider(secretsManager)),
+		WithProvider(SchemeSSM, SSMProvider(parameters)),
+	}
+	if d, err := time.ParseDuration(ttl); err == nil {
+		options = append(options, WithTTL(d))
+	}
+
+	return NewResolver(options...)
+}
+
+var (
+	defaultResolver     *Resolver
+	defaultResolverOnce sync.Once
+)
+
+// Default returns the process wide resolver.
+func Default() *Resolver {
+	defaultResolverOnce.Do(func() {
+		defaultResolver = NewDefaultResolver()
+	})
+	return defaultResolver
+}
+
+// Get resolves name with the Default resolver.
+func Get(ctx context.Context, name string) (string, error) {
+	return Default().Get(ctx, name)
+}
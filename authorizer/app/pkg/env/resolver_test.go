@@ -0,0 +1,154 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeLocalStore writes the file-backed stand-in of Secrets Manager and SSM.
+func writeLocalStore(t *testing.T, content string) *LocalStore {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return &LocalStore{Path: path}
+}
+
+func newLocalResolver(t *testing.T, store *LocalStore, options ...ResolverOption) *Resolver {
+	t.Helper()
+	options = append([]ResolverOption{
+		WithProvider(SchemeFile, FileProvider()),
+		WithProvider(SchemeSecretsManager, SecretsManagerProvider(store)),
+		WithProvider(SchemeSSM, SSMProvider(store)),
+	}, options...)
+	return NewResolver(options...)
+}
+
+func TestResolverGet(t *testing.T) {
+	store := writeLocalStore(t, `{
+		"secretsmanager": {"authorizer/documentdb": "{\"uri\":\"mongodb://secret\"}", "authorizer/plain": "plain-secret"},
+		"ssm": {"/authorizer/level": "DEBUG"}
+	}`)
+	secretFile := filepath.Join(t.TempDir(), "uri")
+	if err := os.WriteFile(secretFile, []byte("mongodb://file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	resolver := newLocalResolver(t, store)
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"mongodb://plain", "mongodb://plain"},
+		{"unknown:scheme", "unknown:scheme"},
+		{"file:" + secretFile, "mongodb://file"},
+		{"secretsmanager:authorizer/documentdb#uri", "mongodb://secret"},
+		{"secretsmanager:authorizer/plain", "plain-secret"},
+		{"ssm:/authorizer/level", "DEBUG"},
+	}
+	for _, test := range tests {
+		t.Setenv("USER_VAR_TEST_VALUE", test.value)
+		got, err := resolver.Get(context.Background(), "USER_VAR_TEST_VALUE")
+		if err != nil {
+			t.Errorf("Get(%q) failed: %v", test.value, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Get(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestResolverErrors(t *testing.T) {
+	store := writeLocalStore(t, `{"secretsmanager": {"authorizer/documentdb": "{\"uri\":\"mongodb://secret\"}"}}`)
+	resolver := newLocalResolver(t, store)
+
+	for _, value := range []string{
+		"secretsmanager:authorizer/missing",
+		"secretsmanager:authorizer/documentdb#password",
+		"ssm:/authorizer/missing",
+		"file:/does/not/exist",
+	} {
+		t.Setenv("USER_VAR_TEST_VALUE", value)
+		if _, err := resolver.Get(context.Background(), "USER_VAR_TEST_VALUE"); err == nil {
+			t.Errorf("Get(%q) expected an error", value)
+		}
+	}
+}
+
+func TestResolverCache(t *testing.T) {
+	now := time.Date(2024, 6, 3, 10, 0, 0, 0, time.UTC)
+	calls := 0
+	var failure error
+	provider := ProviderFunc(func(ctx context.Context, ref string) (string, error) {
+		calls++
+		if failure != nil {
+			return "", failure
+		}
+		return ref + "-" + string(rune('0'+calls)), nil
+	})
+	resolver := NewResolver(
+		WithProvider("test", provider),
+		WithTTL(time.Minute),
+		WithNow(func() time.Time { return now }),
+	)
+	t.Setenv("USER_VAR_TEST_VALUE", "test:secret")
+
+	get := func() string {
+		t.Helper()
+		value, err := resolver.Get(context.Background(), "USER_VAR_TEST_VALUE")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+
+	if value := get(); value != "secret-1" {
+		t.Errorf("expected secret-1, got %s", value)
+	}
+	if value := get(); value != "secret-1" || calls != 1 {
+		t.Errorf("expected the cached secret-1 without calls, got %s after %d calls", value, calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if value := get(); value != "secret-2" {
+		t.Errorf("expected secret-2 once the TTL expired, got %s", value)
+	}
+
+	now = now.Add(2 * time.Minute)
+	failure = errors.New("throttled")
+	if value := get(); value != "secret-2" {
+		t.Errorf("expected the last value when the refresh fails, got %s", value)
+	}
+}
+
+func TestLocalStoreReadsEdits(t *testing.T) {
+	store := writeLocalStore(t, `{"ssm": {"/authorizer/level": "INFO"}}`)
+	resolver := newLocalResolver(t, store, WithTTL(0))
+	t.Setenv("USER_VAR_TEST_VALUE", "ssm:/authorizer/level")
+
+	if value, _ := resolver.Get(context.Background(), "USER_VAR_TEST_VALUE"); value != "INFO" {
+		t.Errorf("expected INFO, got %s", value)
+	}
+	if err := os.WriteFile(store.Path, []byte(`{"ssm": {"/authorizer/level": "DEBUG"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := resolver.Get(context.Background(), "USER_VAR_TEST_VALUE"); value != "DEBUG" {
+		t.Errorf("expected DEBUG after the edit, got %s", value)
+	}
+}
+
+func TestLocalStoreInvalidFile(t *testing.T) {
+	store := writeLocalStore(t, `not json`)
+	resolver := newLocalResolver(t, store)
+	t.Setenv("USER_VAR_TEST_VALUE", "ssm:/authorizer/level")
+
+	if _, err := resolver.Get(context.Background(), "USER_VAR_TEST_VALUE"); err == nil {
+		t.Error("expected an error for an invalid local secrets file")
+	}
+}
@@ -1,29 +1,49 @@
 package errorx
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"runtime"
+	"strings"
 )
 
+// maxStackDepth bounds the frames kept by WithStack.
+const maxStackDepth = 32
+
 type (
 	// ErrorCode defines supported error codes.
 	ErrorCode uint
 
-	// Error interface represents an wrap error.
+	// Error interface represents an wrap error. Errors are immutable, the
+	// With methods return a copy so package level errors can be shared
+	// across requests.
 	Error interface {
 		Error() string
-		SetOrigin(orig error) Error
 		Unwrap() error
+		Is(target error) bool
 		Code() ErrorCode
 		Message() string
-		SetMessage(message string) Error
+		Fields() map[string]interface{}
+		Stack() []runtime.Frame
+		WithOrigin(orig error) Error
+		WithMessage(message string) Error
+		WithField(key string, value interface{}) Error
+		WithStack() Error
 	}
 
 	// ObjectError is the default wrap error
 	// that implements the Error interface.
 	ObjectError struct {
-		orig error
-		msg  string
-		code ErrorCode
+		orig   error
+		msg    string
+		code   ErrorCode
+		fields map[string]interface{}
+		stack  []uintptr
+		// root is the error the copies descend from, nil on that error
+		// itself. Same compares it.
+		root *ObjectError
 	}
 )
 
@@ -41,6 +61,12 @@ func NewErrorf(code ErrorCode, format string, a ...interface{}) Error {
 	return WrapErrorf(nil, code, format, a...)
 }
 
+// New instantiates a new error with a plain message, errors.Is(err,
+// New(code, "")) reports whether err carries code.
+func New(code ErrorCode, message string) Error {
+	return &ObjectError{code: code, msg: message}
+}
+
 // Error returns the message, when wrapping errors the wrapped error is returned.
 func (e *ObjectError) Error() string {
 	if e.orig != nil {
@@ -50,17 +76,34 @@ func (e *ObjectError) Error() string {
 	return e.msg
 }
 
-// SetError set the error's origin.
-func (e *ObjectError) SetOrigin(orig error) Error {
-	e.orig = orig
-	return e
-}
-
 // Unwrap returns the wrapped error, if any.
 func (e *ObjectError) Unwrap() error {
 	return e.orig
 }
 
+// Is reports whether target is an Error with the same code, so errors.Is
+// matches a sentinel whatever message, origin or fields the returned error
+// carries. Sentinels sharing a code match each other, Same tells them apart.
+func (e *ObjectError) Is(target error) bool {
+	t, ok := target.(Error)
+	return ok && t.Code() == e.code
+}
+
+// Same reports whether err, or an error it wraps, is target or a copy of
+// it. Unlike errors.Is it does not match other errors with the same code.
+func Same(err, target error) bool {
+	t, ok := target.(*ObjectError)
+	if !ok {
+		return false
+	}
+	for ; err != nil; err = errors.Unwrap(err) {
+		if e, ok := err.(*ObjectError); ok && e.origin() == t.origin() {
+			return true
+		}
+	}
+	return false
+}
+
 // Code returns the code representing this error.
 func (e *ObjectError) Code() ErrorCode {
 	return e.code
@@ -71,8 +114,106 @@ func (e *ObjectError) Message() string {
 	return e.msg
 }
 
-// SetMessage set the error's message.
-func (e *ObjectError) SetMessage(message string) Error {
-	e.msg = message
+// Fields returns a copy of the error's structured fields.
+func (e *ObjectError) Fields() map[string]interface{} {
+	fields := make(map[string]interface{}, len(e.fields))
+	for key, value := range e.fields {
+		fields[key] = value
+	}
+	return fields
+}
+
+// Stack returns the frames captured by WithStack, if any.
+func (e *ObjectError) Stack() []runtime.Frame {
+	if len(e.stack) == 0 {
+		return nil
+	}
+
+	var stack []runtime.Frame
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, frame)
+		if !more {
+			return stack
+		}
+	}
+}
+
+// WithOrigin returns a copy of the error wrapping orig.
+func (e *ObjectError) WithOrigin(orig error) Error {
+	c := e.clone()
+	c.orig = orig
+	return c
+}
+
+// WithMessage returns a copy of the error with another message.
+func (e *ObjectError) WithMessage(message string) Error {
+	c := e.clone()
+	c.msg = message
+	return c
+}
+
+// WithField returns a copy of the error with the field added.
+func (e *ObjectError) WithField(key string, value interface{}) Error {
+	c := e.clone()
+	c.fields = e.Fields()
+	c.fields[key] = value
+	return c
+}
+
+// WithStack returns a copy of the error holding the stack of its caller.
+func (e *ObjectError) WithStack() Error {
+	c := e.clone()
+	c.stack = make([]uintptr, maxStackDepth)
+	c.stack = c.stack[:runtime.Callers(2, c.stack)]
+	return c
+}
+
+func (e *ObjectError) clone() *ObjectError {
+	c := *e
+	c.root = e.origin()
+	return &c
+}
+
+// origin returns the error created by WrapErrorf or New that e descends from.
+func (e *ObjectError) origin() *ObjectError {
+	if e.root != nil {
+		return e.root
+	}
 	return e
 }
+
+// Format prints the error, %+v adds the fields and the stack.
+func (e *ObjectError) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		io.WriteString(s, e.Error())
+		for key, value := range e.fields {
+			fmt.Fprintf(s, " %s=%v", key, value)
+		}
+		for _, frame := range e.Stack() {
+			fmt.Fprintf(s, "\n%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+		}
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		io.WriteString(s, e.Error())
+	}
+}
+
+type jsonError struct {
+	Code    ErrorCode              `json:"code"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// MarshalJSON writes the code, message and fields of the error. The origin
+// and stack are left out, they are for logs and not for clients.
+func (e *ObjectError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonError{
+		Code:    e.code,
+		Message: strings.TrimSpace(e.msg),
+		Fields:  e.fields,
+	})
+}
@@ -0,0 +1,105 @@
+package errorx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	codeNotFound ErrorCode = iota + 1
+	codeInvalid
+)
+
+var errNotFound = NewErrorf(codeNotFound, "not found")
+
+var errGone = NewErrorf(codeNotFound, "gone")
+
+func TestIsMatchesByCode(t *testing.T) {
+	err := fmt.Errorf("reading key: %w", errNotFound.WithMessage("key abc not found").WithField("key", "abc").WithOrigin(errors.New("origin")))
+
+	if !errors.Is(err, errNotFound) {
+		t.Error("errors.Is did not match a copy of the sentinel")
+	}
+	if !errors.Is(err, New(codeNotFound, "")) {
+		t.Error("errors.Is did not match the code")
+	}
+	if !errors.Is(err, errGone) {
+		t.Error("errors.Is did not match another sentinel with the same code")
+	}
+	if errors.Is(err, New(codeInvalid, "")) || errors.Is(err, errors.New("not found")) {
+		t.Error("errors.Is matched an error with another code")
+	}
+
+	var errx Error
+	if !errors.As(err, &errx) || errx.Message() != "key abc not found" || errx.Code() != codeNotFound {
+		t.Errorf("errors.As = %v", errx)
+	}
+}
+
+func TestSameMatchesTheSentinel(t *testing.T) {
+	err := fmt.Errorf("reading key: %w", errNotFound.WithMessage("key abc not found").WithField("key", "abc"))
+
+	if !Same(err, errNotFound) || !Same(err, errNotFound.WithMessage("other")) {
+		t.Error("Same did not match a copy of the sentinel")
+	}
+	if Same(err, errGone) || Same(errGone, errNotFound) || Same(New(codeNotFound, "not found"), errNotFound) {
+		t.Error("Same matched an error that is not a copy of the sentinel")
+	}
+	if Same(err, errors.New("not found")) || Same(nil, errNotFound) {
+		t.Error("Same matched an error that is not an Error")
+	}
+}
+
+func TestWithDoesNotMutate(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := errNotFound.WithMessage(fmt.Sprint(i)).WithField("i", i).WithOrigin(errors.New("origin"))
+			if err.Message() != fmt.Sprint(i) || err.Fields()["i"] != i {
+				t.Errorf("copy %d = %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if errNotFound.Message() != "not found" || errNotFound.Unwrap() != nil || len(errNotFound.Fields()) != 0 {
+		t.Errorf("sentinel was mutated: %+v", errNotFound)
+	}
+}
+
+func TestWithStack(t *testing.T) {
+	if errNotFound.Stack() != nil {
+		t.Error("stack captured without WithStack")
+	}
+
+	err := errNotFound.WithStack()
+	stack := err.Stack()
+	if len(stack) == 0 || !strings.HasSuffix(stack[0].Function, "TestWithStack") {
+		t.Fatalf("stack = %v", stack)
+	}
+	if !strings.Contains(fmt.Sprintf("%+v", err), "errorx_test.go") {
+		t.Errorf("%%+v does not print the stack: %+v", err)
+	}
+	if fmt.Sprintf("%v", err) != "not found" {
+		t.Errorf("%%v = %v", err)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	err := errNotFound.WithOrigin(errors.New("mongo: no documents")).WithField("clientId", "client-1")
+
+	data, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+	want := `{"code":1,"message":"not found","fields":{"clientId":"client-1"}}`
+	if string(data) != want {
+		t.Errorf("json = %s, want %s", data, want)
+	}
+}
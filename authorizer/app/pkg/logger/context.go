@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/sirupsen/logrus"
+)
+
+// RequestIDField is the field carrying the Lambda request id.
+const RequestIDField = "aws_request_id"
+
+// ContextHook adds the Lambda request id to entries logged with a context,
+// so every line of an invocation can be correlated.
+type ContextHook struct{}
+
+func (ContextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (ContextHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if _, ok := entry.Data[RequestIDField]; ok {
+		return nil
+	}
+	if lc, ok := lambdacontext.FromContext(entry.Context); ok {
+		entry.Data[RequestIDField] = lc.AwsRequestID
+	}
+	return nil
+}
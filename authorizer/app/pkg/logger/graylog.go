@@ -0,0 +1,289 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxBuffered bounds the entries kept between flushes, a full buffer
+	// drops its oldest entries.
+	maxBuffered = 500
+	// flushTimeout bounds a flush when the context has no earlier deadline.
+	flushTimeout = 2 * time.Second
+	// maxIdle is how long a connection is reused without writes. Lambda
+	// freezes the environment between invocations and the other end, or
+	// anything in between, may close the connection meanwhile.
+	maxIdle = time.Minute
+
+	chunkSize = 1420
+	maxChunks = 128
+)
+
+// GraylogAddr is where GELF messages are sent, GRAYLOG_ADDR is either
+// "udp://host:port", "tcp://host:port" or "host:port" for UDP.
+type GraylogAddr struct {
+	Network string
+	Address string
+}
+
+func ParseGraylogAddr(addr string) (GraylogAddr, error) {
+	graylogAddr := GraylogAddr{Network: "udp", Address: addr}
+	if network, address, ok := strings.Cut(addr, "://"); ok {
+		graylogAddr = GraylogAddr{Network: strings.ToLower(network), Address: address}
+	}
+	if graylogAddr.Network != "udp" && graylogAddr.Network != "tcp" {
+		return graylogAddr, fmt.Errorf("not a valid graylog protocol: %s", graylogAddr.Network)
+	}
+
+	host, port, err := net.SplitHostPort(graylogAddr.Address)
+	if err != nil {
+		return graylogAddr, fmt.Errorf("not a valid graylog address %q: %w", addr, err)
+	}
+	if n, err := strconv.Atoi(port); host == "" || err != nil || n <= 0 || n > 65535 {
+		return graylogAddr, fmt.Errorf("not a valid graylog address: %s", addr)
+	}
+	return graylogAddr, nil
+}
+
+// GraylogHook sends entries as GELF over UDP or TCP. Entries are buffered
+// and only sent on Flush, which must run at the end of every invocation
+// before Lambda freezes the environment or they would be lost.
+type GraylogHook struct {
+	mu        sync.Mutex
+	addr      GraylogAddr
+	host      string
+	facility  string
+	extra     map[string]interface{}
+	conn      net.Conn
+	lastWrite time.Time
+	buffer    [][]byte
+	dropped   int
+}
+
+func NewGraylogHook(addr GraylogAddr, facility string, extra map[string]interface{}) *GraylogHook {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+
+	return &GraylogHook{
+		addr:     addr,
+		host:     host,
+		facility: facility,
+		extra:    extra,
+	}
+}
+
+func (h *GraylogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *GraylogHook) Fire(entry *logrus.Entry) error {
+	message, err := h.message(entry)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.buffer) >= maxBuffered {
+		h.buffer = h.buffer[1:]
+		h.dropped++
+	}
+	h.buffer = append(h.buffer, message)
+	return nil
+}
+
+// Flush sends the buffered entries, those that cannot be sent are dropped.
+func (h *GraylogHook) Flush(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.flush(ctx)
+}
+
+func (h *GraylogHook) flush(ctx context.Context) error {
+	dropped := h.dropped
+	h.dropped = 0
+	if len(h.buffer) == 0 {
+		return droppedError(dropped)
+	}
+	messages := h.buffer
+	h.buffer = nil
+
+	if h.conn != nil && time.Since(h.lastWrite) > maxIdle {
+		h.close()
+	}
+
+	// A connection closed while the environment was frozen only fails on
+	// write, the rest is sent once more over a new one.
+	sent, err := h.send(ctx, messages)
+	if err != nil {
+		var resent int
+		resent, err = h.send(ctx, messages[sent:])
+		sent += resent
+	}
+	if err != nil {
+		return fmt.Errorf("error sending to graylog, %d entries dropped: %w", len(messages)-sent+dropped, err)
+	}
+	return droppedError(dropped)
+}
+
+// send writes messages over the connection, dialing it when there is none,
+// and returns how many were sent. The connection is closed on error.
+func (h *GraylogHook) send(ctx context.Context, messages [][]byte) (int, error) {
+	if h.conn == nil {
+		conn, err := (&net.Dialer{Timeout: flushTimeout}).DialContext(ctx, h.addr.Network, h.addr.Address)
+		if err != nil {
+			return 0, fmt.Errorf("error connecting to graylog: %w", err)
+		}
+		h.conn = conn
+	}
+
+	deadline := time.Now().Add(flushTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	h.conn.SetWriteDeadline(deadline)
+
+	for i, message := range messages {
+		if err := h.write(message); err != nil {
+			h.close()
+			return i, err
+		}
+		h.lastWrite = time.Now()
+	}
+	return len(messages), nil
+}
+
+func (h *GraylogHook) close() {
+	h.conn.Close()
+	h.conn = nil
+}
+
+func droppedError(dropped int) error {
+	if dropped == 0 {
+		return nil
+	}
+	return fmt.Errorf("graylog buffer full, %d entries dropped", dropped)
+}
+
+// write sends a message as one null terminated frame over TCP, and as one
+// datagram, or GELF chunks when it does not fit, over UDP.
+func (h *GraylogHook) write(message []byte) error {
+	if h.addr.Network == "tcp" {
+		_, err := h.conn.Write(append(message, 0))
+		return err
+	}
+
+	if len(message) <= chunkSize {
+		_, err := h.conn.Write(message)
+		return err
+	}
+
+	count := (len(message) + chunkSize - 1) / chunkSize
+	if count > maxChunks {
+		return fmt.Errorf("message of %d bytes is too large for GELF over UDP", len(message))
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		end := (i + 1) * chunkSize
+		if end > len(message) {
+			end = len(message)
+		}
+		var chunk bytes.Buffer
+		chunk.Write([]byte{0x1e, 0x0f})
+		chunk.Write(id)
+		chunk.Write([]byte{byte(i), byte(count)})
+		chunk.Write(message[i*chunkSize : end])
+		if _, err := h.conn.Write(chunk.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// message builds the GELF 1.1 payload of entry, its fields and the extra
+// fields become additional "_" fields.
+func (h *GraylogHook) message(entry *logrus.Entry) ([]byte, error) {
+	short, full := entry.Message, ""
+	if i := strings.IndexRune(short, '\n'); i > 0 {
+		short, full = short[:i], short
+	}
+
+	message := map[string]interface{}{
+		"version":       "1.1",
+		"host":          h.host,
+		"short_message": short,
+		"timestamp":     float64(entry.Time.UnixNano()) / float64(time.Second),
+		"level":         syslogLevel(entry.Level),
+		"_facility":     h.facility,
+	}
+	if full != "" {
+		message["full_message"] = full
+	}
+	if entry.Caller != nil {
+		message["_file"] = entry.Caller.File
+		message["_line"] = entry.Caller.Line
+	}
+	for key, value := range h.extra {
+		message["_"+key] = value
+	}
+	for key, value := range entry.Data {
+		if key == "id" {
+			key = "field_id"
+		}
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		message["_"+key] = value
+	}
+
+	return json.Marshal(message)
+}
+
+func syslogLevel(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel:
+		return 0
+	case logrus.FatalLevel:
+		return 2
+	case logrus.ErrorLevel:
+		return 3
+	case logrus.WarnLevel:
+		return 4
+	case logrus.InfoLevel:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// Flush sends what the Graylog hooks of log still buffer.
+func Flush(ctx context.Context, log *logrus.Logger) error {
+	for _, hook := range log.Hooks[logrus.InfoLevel] {
+		if graylog, ok := hook.(*GraylogHook); ok {
+			if err := graylog.Flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
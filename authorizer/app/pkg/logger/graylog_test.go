@@ -0,0 +1,229 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newGraylogLogger(hook *GraylogHook) *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	log.AddHook(hook)
+	return log
+}
+
+func TestGraylogUDPSendsOnFlush(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	hook := NewGraylogHook(GraylogAddr{Network: "udp", Address: conn.LocalAddr().String()}, "authorizer", map[string]interface{}{"env": "test"})
+	log := newGraylogLogger(hook)
+	log.WithField("client_id", "client-1").Info("Apikey validated")
+
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, _, err := conn.ReadFrom(make([]byte, 1)); err == nil {
+		t.Fatal("message sent before flush")
+	}
+
+	if err := Flush(context.Background(), log); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 8192)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var message map[string]interface{}
+	if err := json.Unmarshal(buf[:n], &message); err != nil {
+		t.Fatalf("not a GELF message %q: %v", buf[:n], err)
+	}
+	if message["short_message"] != "Apikey validated" || message["_facility"] != "authorizer" ||
+		message["_env"] != "test" || message["_client_id"] != "client-1" || message["level"] != float64(6) {
+		t.Errorf("message = %v", message)
+	}
+}
+
+func TestGraylogTCPFramesMessages(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		var frames []string
+		for len(frames) < 2 {
+			frame, err := reader.ReadString(0)
+			if err != nil {
+				break
+			}
+			frames = append(frames, frame[:len(frame)-1])
+		}
+		received <- frames
+	}()
+
+	hook := NewGraylogHook(GraylogAddr{Network: "tcp", Address: listener.Addr().String()}, "authorizer", nil)
+	log := newGraylogLogger(hook)
+	log.Info("first")
+	log.Error("second")
+	if err := Flush(context.Background(), log); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case frames := <-received:
+		if len(frames) != 2 {
+			t.Fatalf("got %d frames", len(frames))
+		}
+		var message map[string]interface{}
+		if err := json.Unmarshal([]byte(frames[1]), &message); err != nil {
+			t.Fatal(err)
+		}
+		if message["short_message"] != "second" || message["level"] != float64(3) {
+			t.Errorf("message = %v", message)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message received")
+	}
+}
+
+func TestGraylogTCPReconnects(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 3)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				frame, err := bufio.NewReader(conn).ReadString(0)
+				if err != nil {
+					return
+				}
+				var message map[string]interface{}
+				json.Unmarshal([]byte(frame[:len(frame)-1]), &message)
+				received <- message["short_message"].(string)
+			}()
+		}
+	}()
+	receive := func(want string) {
+		t.Helper()
+		select {
+		case got := <-received:
+			if got != want {
+				t.Errorf("received %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%q not received", want)
+		}
+	}
+
+	hook := NewGraylogHook(GraylogAddr{Network: "tcp", Address: listener.Addr().String()}, "authorizer", nil)
+	log := newGraylogLogger(hook)
+	log.Info("first")
+	if err := Flush(context.Background(), log); err != nil {
+		t.Fatal(err)
+	}
+	receive("first")
+
+	// A connection that fails on write is replaced once.
+	hook.conn.Close()
+	log.Info("after a write error")
+	if err := Flush(context.Background(), log); err != nil {
+		t.Fatal(err)
+	}
+	receive("after a write error")
+
+	// An idle connection is not reused.
+	hook.lastWrite = time.Now().Add(-2 * maxIdle)
+	log.Info("after an idle gap")
+	if err := Flush(context.Background(), log); err != nil {
+		t.Fatal(err)
+	}
+	receive("after an idle gap")
+}
+
+func TestGraylogDropsOldestWhenFull(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	hook := NewGraylogHook(GraylogAddr{Network: "udp", Address: conn.LocalAddr().String()}, "authorizer", nil)
+	log := newGraylogLogger(hook)
+	for i := 0; i < maxBuffered+2; i++ {
+		log.Info(strconv.Itoa(i))
+	}
+
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, _, err := conn.ReadFrom(make([]byte, 1)); err == nil {
+		t.Fatal("message sent before flush")
+	}
+
+	if err := Flush(context.Background(), log); err == nil || err.Error() != "graylog buffer full, 2 entries dropped" {
+		t.Errorf("flush = %v, want the dropped entries reported", err)
+	}
+
+	buf := make([]byte, 8192)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var message map[string]interface{}
+	if err := json.Unmarshal(buf[:n], &message); err != nil {
+		t.Fatal(err)
+	}
+	if message["short_message"] != "2" {
+		t.Errorf("first message sent = %v, want the oldest kept", message["short_message"])
+	}
+}
+
+func TestParseGraylogAddr(t *testing.T) {
+	valid := map[string]GraylogAddr{
+		"graylog:12201":        {Network: "udp", Address: "graylog:12201"},
+		"udp://graylog:12201":  {Network: "udp", Address: "graylog:12201"},
+		"tcp://10.0.0.1:12201": {Network: "tcp", Address: "10.0.0.1:12201"},
+	}
+	for value, want := range valid {
+		got, err := ParseGraylogAddr(value)
+		if err != nil || got != want {
+			t.Errorf("ParseGraylogAddr(%q) = %v, %v", value, got, err)
+		}
+	}
+
+	for _, value := range []string{"", "graylog", "http://graylog:12201", "graylog:port", ":12201", "graylog:70000"} {
+		if _, err := ParseGraylogAddr(value); err == nil {
+			t.Errorf("ParseGraylogAddr(%q) did not fail", value)
+		}
+	}
+}
@@ -7,65 +7,101 @@ import (
 	"path"
 	"runtime"
 	"strings"
+	"time"
 
-	graylog "github.com/gemnasium/logrus-graylog-hook/v3"
 	"github.com/sirupsen/logrus"
 )
 
 type Channel int
 
+type Format int
+
 const (
 	Stdout Channel = iota
 	Stdgraylog
 	Stdgrayout
 )
 
+const (
+	Text Format = iota
+	JSON
+)
+
 func New() (*logrus.Logger, error) {
 	var (
 		logChannel  = os.Getenv("USER_VAR_LOG_CHAN")
 		logLevel    = os.Getenv("USER_VAR_LOG_LEVEL")
+		logFormat   = os.Getenv("USER_VAR_LOG_FORMAT")
 		graylogAddr = os.Getenv("GRAYLOG_ADDR")
-		formatter   = &logrus.TextFormatter{
-			TimestampFormat: "2006-01-02 15:04:05",
-			FullTimestamp:   true,
-			DisableColors:   false,
-			CallerPrettyfier: func(frame *runtime.Frame) (string, string) {
-				function, file := "", fmt.Sprintf("%s:%d", path.Base(frame.File), frame.Line)
-				return function, file
-			},
-		}
 	)
 	channel, err := ParseChannel(logChannel)
 	if err != nil {
 		return nil, err
 	}
 
+	format, err := ParseFormat(logFormat)
+	if err != nil {
+		return nil, err
+	}
+
 	level, err := logrus.ParseLevel(logLevel)
 	if err != nil {
 		return nil, err
 	}
 
 	log := logrus.New()
-	log.SetFormatter(formatter)
+	log.SetFormatter(NewFormatter(format))
+	log.AddHook(ContextHook{})
 	log.SetReportCaller(true)
 	log.SetLevel(level)
 
 	switch channel {
 	case Stdout:
 		log.SetOutput(os.Stdout)
-	case Stdgraylog:
-		hook := graylog.NewGraylogHook(graylogAddr, nil)
-		log.AddHook(hook)
-		log.SetOutput(io.Discard)
-	case Stdgrayout:
-		hook := graylog.NewGraylogHook(graylogAddr, nil)
-		log.AddHook(hook)
-		log.SetOutput(os.Stdout)
+	case Stdgraylog, Stdgrayout:
+		addr, err := ParseGraylogAddr(graylogAddr)
+		if err != nil {
+			return nil, err
+		}
+		extra, err := ParseExtraFields(os.Getenv("GRAYLOG_EXTRA_FIELDS"))
+		if err != nil {
+			return nil, err
+		}
+		log.AddHook(NewGraylogHook(addr, facility(os.Getenv("GRAYLOG_FACILITY")), extra))
+		if channel == Stdgraylog {
+			log.SetOutput(io.Discard)
+		} else {
+			log.SetOutput(os.Stdout)
+		}
 	}
 
 	return log, nil
 }
 
+func facility(value string) string {
+	if value == "" {
+		return "apikey-authorizer"
+	}
+	return value
+}
+
+// ParseExtraFields reads the fields added to every Graylog message from
+// GRAYLOG_EXTRA_FIELDS, e.g. "env=prod,team=payments".
+func ParseExtraFields(value string) (map[string]interface{}, error) {
+	extra := map[string]interface{}{}
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("not a valid graylog extra field: %s", field)
+		}
+		extra[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return extra, nil
+}
+
 func ParseChannel(channel string) (Channel, error) {
 	switch strings.ToLower(channel) {
 	case "stdout":
@@ -78,3 +114,35 @@ func ParseChannel(channel string) (Channel, error) {
 	var ch Channel
 	return ch, fmt.Errorf("not a valid channel: %s", channel)
 }
+
+// ParseFormat reads USER_VAR_LOG_FORMAT, text when it is not set.
+func ParseFormat(format string) (Format, error) {
+	switch strings.ToLower(format) {
+	case "", "text":
+		return Text, nil
+	case "json":
+		return JSON, nil
+	}
+	var f Format
+	return f, fmt.Errorf("not a valid log format: %s", format)
+}
+
+func NewFormatter(format Format) logrus.Formatter {
+	callerPrettyfier := func(frame *runtime.Frame) (string, string) {
+		function, file := "", fmt.Sprintf("%s:%d", path.Base(frame.File), frame.Line)
+		return function, file
+	}
+
+	if format == JSON {
+		return &logrus.JSONFormatter{
+			TimestampFormat:  time.RFC3339Nano,
+			CallerPrettyfier: callerPrettyfier,
+		}
+	}
+	return &logrus.TextFormatter{
+		TimestampFormat:  "2006-01-02 15:04:05",
+		FullTimestamp:    true,
+		DisableColors:    false,
+		CallerPrettyfier: callerPrettyfier,
+	}
+}
@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/sirupsen/logrus"
+)
+
+func TestJSONEntryCarriesRequestID(t *testing.T) {
+	var out bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&out)
+	log.SetFormatter(NewFormatter(JSON))
+	log.AddHook(ContextHook{})
+
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "req-1"})
+	log.WithContext(ctx).WithField("client_id", "client-1").Info("Apikey validated")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("not a JSON entry %q: %v", out.String(), err)
+	}
+	if entry[RequestIDField] != "req-1" || entry["client_id"] != "client-1" || entry["msg"] != "Apikey validated" {
+		t.Errorf("entry = %v", entry)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for value, want := range map[string]Format{"": Text, "text": Text, "JSON": JSON} {
+		got, err := ParseFormat(value)
+		if err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %v, %v", value, got, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(xml) did not fail")
+	}
+}
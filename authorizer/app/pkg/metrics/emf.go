@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// emf writes each metric as a CloudWatch Embedded Metric Format line, which
+// CloudWatch Logs turns into a metric without any SDK call.
+type emf struct {
+	mu              sync.Mutex
+	out             io.Writer
+	namespace       string
+	highCardinality map[string]bool
+	now             func() time.Time
+}
+
+type EMFOption func(*emf)
+
+// WithHighCardinality keeps the given dimensions as plain properties of the
+// log line, searchable in Logs Insights but not billed as metric series.
+func WithHighCardinality(dimensions ...string) EMFOption {
+	return func(e *emf) {
+		for _, dimension := range dimensions {
+			e.highCardinality[dimension] = true
+		}
+	}
+}
+
+func NewEMF(out io.Writer, namespace string, options ...EMFOption) Metrics {
+	e := &emf{
+		out:             out,
+		namespace:       namespace,
+		highCardinality: map[string]bool{},
+		now:             time.Now,
+	}
+
+	for _, option := range options {
+		option(e)
+	}
+
+	return e
+}
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit Unit   `json:"Unit"`
+}
+
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+func (e *emf) Put(name string, value float64, unit Unit, dimensions Dimensions) {
+	line := map[string]interface{}{}
+	keys := []string{}
+	for key, dimension := range dimensions {
+		if dimension == "" {
+			continue
+		}
+		line[key] = dimension
+		if !e.highCardinality[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	line[name] = value
+	line["_aws"] = emfMetadata{
+		Timestamp: e.now().UnixMilli(),
+		CloudWatchMetrics: []emfDirective{{
+			Namespace:  e.namespace,
+			Dimensions: [][]string{keys},
+			Metrics:    []emfMetric{{Name: name, Unit: unit}},
+		}},
+	}
+
+	data, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.out.Write(append(data, '\n'))
+}
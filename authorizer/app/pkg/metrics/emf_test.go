@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEMFLine(t *testing.T) {
+	var out bytes.Buffer
+	m := NewEMF(&out, "ApiKeyAuthorizer", WithHighCardinality(ClientID)).(*emf)
+	m.now = func() time.Time { return time.UnixMilli(1760788800000) }
+
+	m.Put(Decision, 1, Count, Dimensions{Reason: "QUOTA_EXCEEDED", Plan: "premium", ClientID: "client-1"})
+
+	var line struct {
+		AWS struct {
+			Timestamp         int64 `json:"Timestamp"`
+			CloudWatchMetrics []struct {
+				Namespace  string
+				Dimensions [][]string
+				Metrics    []emfMetric
+			}
+		} `json:"_aws"`
+		Decision float64
+		Reason   string
+		Plan     string
+		ClientID string `json:"ClientId"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("invalid EMF line %q: %v", out.String(), err)
+	}
+
+	if line.AWS.Timestamp != 1760788800000 {
+		t.Errorf("timestamp = %d", line.AWS.Timestamp)
+	}
+	directive := line.AWS.CloudWatchMetrics[0]
+	if directive.Namespace != "ApiKeyAuthorizer" {
+		t.Errorf("namespace = %q", directive.Namespace)
+	}
+	if got := directive.Dimensions[0]; len(got) != 2 || got[0] != Plan || got[1] != Reason {
+		t.Errorf("dimensions = %v, want [Plan Reason]", got)
+	}
+	if directive.Metrics[0] != (emfMetric{Name: Decision, Unit: Count}) {
+		t.Errorf("metrics = %v", directive.Metrics)
+	}
+	if line.Decision != 1 || line.Reason != "QUOTA_EXCEEDED" || line.Plan != "premium" || line.ClientID != "client-1" {
+		t.Errorf("values = %+v", line)
+	}
+}
+
+func TestEMFSkipsEmptyDimensions(t *testing.T) {
+	var out bytes.Buffer
+	NewEMF(&out, "ApiKeyAuthorizer").Put(ValidationLatency, 12.5, Milliseconds, Dimensions{Plan: ""})
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := line[Plan]; ok {
+		t.Errorf("empty dimension written: %s", out.String())
+	}
+	if line[ValidationLatency] != 12.5 {
+		t.Errorf("value = %v", line[ValidationLatency])
+	}
+}
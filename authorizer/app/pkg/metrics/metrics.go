@@ -0,0 +1,52 @@
+package metrics
+
+import "time"
+
+type Unit string
+
+const (
+	Count        Unit = "Count"
+	Milliseconds Unit = "Milliseconds"
+	None         Unit = "None"
+)
+
+// Names of the metrics the authorizer emits.
+const (
+	Decision          = "Decision"
+	ValidationLatency = "ValidationLatency"
+	DBLatency         = "DBLatency"
+	CacheHit          = "CacheHit"
+	QuotaRejection    = "QuotaRejection"
+)
+
+// Names of the dimensions the metrics are split by.
+const (
+	ClientID  = "ClientId"
+	Plan      = "Plan"
+	Reason    = "Reason"
+	Operation = "Operation"
+	Cache     = "Cache"
+	Window    = "Window"
+)
+
+// Allow is the Reason of an allowed call.
+const Allow = "ALLOW"
+
+type Dimensions map[string]string
+
+// Metrics records a single value of a metric.
+type Metrics interface {
+	Put(name string, value float64, unit Unit, dimensions Dimensions)
+}
+
+// Since returns the milliseconds elapsed since start.
+func Since(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
+
+type nop struct{}
+
+// Nop discards every metric.
+var Nop Metrics = nop{}
+
+func (nop) Put(string, float64, Unit, Dimensions) {}